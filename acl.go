@@ -0,0 +1,535 @@
+package decoder
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// Group URIs of the S3 predefined groups.
+const (
+	GroupURIAllUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	GroupURIAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// refererWildcard is the referer of the swift ".r:*" ACL, which RGW also
+// grants to all users.
+const refererWildcard = "*"
+
+// ACLGranteeType is an ACLGranteeTypeEnum.
+type ACLGranteeType uint32
+
+const (
+	ACLTypeCanonUser ACLGranteeType = iota
+	ACLTypeEmailUser
+	ACLTypeGroup
+	ACLTypeUnknown
+	ACLTypeReferer
+)
+
+// ACLGroupType is an ACLGroupTypeEnum.
+type ACLGroupType uint32
+
+const (
+	ACLGroupNone ACLGroupType = iota
+	ACLGroupAllUsers
+	ACLGroupAuthenticatedUsers
+)
+
+// URI returns the S3 URI of the group, or "" for ACLGroupNone.
+func (g ACLGroupType) URI() string {
+	switch g {
+	case ACLGroupAllUsers:
+		return GroupURIAllUsers
+	case ACLGroupAuthenticatedUsers:
+		return GroupURIAuthenticatedUsers
+	}
+	return ""
+}
+
+func groupFromURI(uri string) ACLGroupType {
+	switch uri {
+	case GroupURIAllUsers:
+		return ACLGroupAllUsers
+	case GroupURIAuthenticatedUsers:
+		return ACLGroupAuthenticatedUsers
+	}
+	return ACLGroupNone
+}
+
+// ACLOwner is an ACLOwner.
+type ACLOwner struct {
+	ID          RGWUser
+	DisplayName string
+}
+
+// ACLGrant is an ACLGrant. Which of ID, Email, Group and URLSpec is set
+// depends on Type; Permission holds the Perm* flags.
+type ACLGrant struct {
+	Type       ACLGranteeType
+	ID         RGWUser
+	Email      string
+	Permission int32
+	Name       string
+	Group      ACLGroupType
+	URLSpec    string
+}
+
+// ACLGrantEntry is an entry of the grant_map multimap of a
+// RGWAccessControlList.
+type ACLGrantEntry struct {
+	Key   string
+	Grant ACLGrant
+}
+
+// ACLReferer is an ACLReferer, a swift referer grant.
+type ACLReferer struct {
+	URLSpec string
+	Perm    uint32
+}
+
+// RGWAccessControlList is a RGWAccessControlList. UserMap and GroupMap are
+// the permissions of every grantee, merged from GrantMap.
+type RGWAccessControlList struct {
+	UserMap     map[string]int32
+	GroupMap    map[ACLGroupType]int32
+	GrantMap    []ACLGrantEntry
+	RefererList []ACLReferer
+}
+
+// RGWAccessControlPolicy is a RGWAccessControlPolicy, the ACL of a bucket or
+// object stored in its user.rgw.acl xattr.
+type RGWAccessControlPolicy struct {
+	ACL   RGWAccessControlList
+	Owner ACLOwner
+}
+
+func DecodeRGWAccessControlPolicy(data []byte, opts ...DecodeOptions) (*RGWAccessControlPolicy, error) {
+	d := NewDecoder(data, opts...)
+	p, err := d.DecodeRGWAccessControlPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return p, d.Done()
+}
+
+// DecodeACLOwner decodes an ACLOwner.
+func (d *Decoder) DecodeACLOwner() (*ACLOwner, error) {
+	var r ACLOwner
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("ACLOwner", 3, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("id")
+	id, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	r.ID = parseRGWUser(id)
+	d.Field("display_name")
+	if r.DisplayName, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeACLGranteeType decodes an ACLGranteeType.
+func (d *Decoder) DecodeACLGranteeType() (ACLGranteeType, error) {
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("ACLGranteeType", 2, 2, 2)
+	if err != nil {
+		return 0, err
+	}
+	d.Field("type")
+	t, err := d.DecodeU32()
+	if err != nil {
+		return 0, err
+	}
+	return ACLGranteeType(t), d.DecodeFinish(structEnd)
+}
+
+// DecodeACLPermission decodes an ACLPermission and returns its flags.
+func (d *Decoder) DecodeACLPermission() (int32, error) {
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("ACLPermission", 2, 2, 2)
+	if err != nil {
+		return 0, err
+	}
+	d.Field("flags")
+	flags, err := d.DecodeI32()
+	if err != nil {
+		return 0, err
+	}
+	return flags, d.DecodeFinish(structEnd)
+}
+
+// DecodeACLGrant decodes an ACLGrant. Grants before v2 only have the group
+// URI, which is mapped to the group.
+func (d *Decoder) DecodeACLGrant() (*ACLGrant, error) {
+	var r ACLGrant
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("ACLGrant", 5, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("type")
+	if r.Type, err = d.DecodeACLGranteeType(); err != nil {
+		return nil, err
+	}
+	d.Field("id")
+	id, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	r.ID = parseRGWUser(id)
+	d.Field("uri")
+	uri, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	d.Field("email")
+	if r.Email, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("permission")
+	if r.Permission, err = d.DecodeACLPermission(); err != nil {
+		return nil, err
+	}
+	d.Field("name")
+	if r.Name, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	if structV > 1 {
+		d.Field("group")
+		g, err := d.DecodeU32()
+		if err != nil {
+			return nil, err
+		}
+		r.Group = ACLGroupType(g)
+	} else {
+		r.Group = groupFromURI(uri)
+	}
+	if structV >= 5 {
+		d.Field("url_spec")
+		if r.URLSpec, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeACLReferer decodes an ACLReferer.
+func (d *Decoder) DecodeACLReferer() (*ACLReferer, error) {
+	var r ACLReferer
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("ACLReferer", 1, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("url_spec")
+	if r.URLSpec, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("perm")
+	if r.Perm, err = d.DecodeU32(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWAccessControlList decodes a RGWAccessControlList. Like Ceph, the
+// user and group maps of a v1 list that was written without them are
+// rebuilt from the grants.
+func (d *Decoder) DecodeRGWAccessControlList() (*RGWAccessControlList, error) {
+	r := RGWAccessControlList{
+		UserMap:  make(map[string]int32),
+		GroupMap: make(map[ACLGroupType]int32),
+	}
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("RGWAccessControlList", 4, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("maps_initialized")
+	mapsInitialized, err := d.DecodeBool()
+	if err != nil {
+		return nil, err
+	}
+	err = d.DecodeMap("acl_user_map", func(i uint32) error {
+		k, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		v, err := d.DecodeI32()
+		if err != nil {
+			return err
+		}
+		r.UserMap[k] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = d.DecodeMap("grant_map", func(i uint32) error {
+		k, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		g, err := d.DecodeACLGrant()
+		if err != nil {
+			return err
+		}
+		r.GrantMap = append(r.GrantMap, ACLGrantEntry{Key: k, Grant: *g})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		err = d.DecodeMap("acl_group_map", func(i uint32) error {
+			k, err := d.DecodeU32()
+			if err != nil {
+				return err
+			}
+			v, err := d.DecodeI32()
+			if err != nil {
+				return err
+			}
+			r.GroupMap[ACLGroupType(k)] = v
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if !mapsInitialized {
+		for i := range r.GrantMap {
+			r.addGrant(&r.GrantMap[i].Grant)
+		}
+	}
+	if structV >= 4 {
+		err = d.DecodeList("referer_list", func(i uint32) error {
+			ref, err := d.DecodeACLReferer()
+			if err != nil {
+				return err
+			}
+			r.RefererList = append(r.RefererList, *ref)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// addGrant merges g into the user and group maps like
+// RGWAccessControlList::_add_grant.
+func (r *RGWAccessControlList) addGrant(g *ACLGrant) {
+	switch g.Type {
+	case ACLTypeReferer:
+		r.RefererList = append(r.RefererList, ACLReferer{URLSpec: g.URLSpec, Perm: uint32(g.Permission)})
+		if g.URLSpec == refererWildcard {
+			r.GroupMap[ACLGroupAllUsers] |= g.Permission
+		}
+	case ACLTypeGroup:
+		r.GroupMap[g.Group] |= g.Permission
+	case ACLTypeEmailUser:
+		r.UserMap[g.Email] |= g.Permission
+	default:
+		r.UserMap[g.ID.String()] |= g.Permission
+	}
+}
+
+// DecodeRGWAccessControlPolicy decodes a RGWAccessControlPolicy.
+func (d *Decoder) DecodeRGWAccessControlPolicy() (*RGWAccessControlPolicy, error) {
+	var r RGWAccessControlPolicy
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("RGWAccessControlPolicy", 2, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("owner")
+	owner, err := d.DecodeACLOwner()
+	if err != nil {
+		return nil, err
+	}
+	r.Owner = *owner
+	d.Field("acl")
+	acl, err := d.DecodeRGWAccessControlList()
+	if err != nil {
+		return nil, err
+	}
+	r.ACL = *acl
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeACLOwner(r *ACLOwner) {
+	start := e.EncodeStart(3, 2)
+	e.EncodeString(r.ID.String())
+	e.EncodeString(r.DisplayName)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeACLGranteeType(t ACLGranteeType) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeU32(uint32(t))
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeACLPermission(flags int32) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeI32(flags)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeACLGrant(r *ACLGrant) {
+	start := e.EncodeStart(5, 3)
+	e.EncodeACLGranteeType(r.Type)
+	e.EncodeString(r.ID.String())
+	// The group URI is no longer written; Group replaces it.
+	e.EncodeString("")
+	e.EncodeString(r.Email)
+	e.EncodeACLPermission(r.Permission)
+	e.EncodeString(r.Name)
+	e.EncodeU32(uint32(r.Group))
+	e.EncodeString(r.URLSpec)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeACLReferer(r *ACLReferer) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeString(r.URLSpec)
+	e.EncodeU32(r.Perm)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWAccessControlList(r *RGWAccessControlList) {
+	start := e.EncodeStart(4, 3)
+	e.EncodeBool(true)
+	users := make([]string, 0, len(r.UserMap))
+	for k := range r.UserMap {
+		users = append(users, k)
+	}
+	sort.Strings(users)
+	e.EncodeList(len(users), func(i int) {
+		e.EncodeString(users[i])
+		e.EncodeI32(r.UserMap[users[i]])
+	})
+	e.EncodeList(len(r.GrantMap), func(i int) {
+		e.EncodeString(r.GrantMap[i].Key)
+		e.EncodeACLGrant(&r.GrantMap[i].Grant)
+	})
+	groups := r.sortedGroups()
+	e.EncodeList(len(groups), func(i int) {
+		e.EncodeU32(uint32(groups[i]))
+		e.EncodeI32(r.GroupMap[groups[i]])
+	})
+	e.EncodeList(len(r.RefererList), func(i int) {
+		e.EncodeACLReferer(&r.RefererList[i])
+	})
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWAccessControlPolicy(r *RGWAccessControlPolicy) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeACLOwner(&r.Owner)
+	e.EncodeRGWAccessControlList(&r.ACL)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWAccessControlPolicy(r *RGWAccessControlPolicy) []byte {
+	e := NewEncoder()
+	e.EncodeRGWAccessControlPolicy(r)
+	return e.Bytes()
+}
+
+func (r *RGWAccessControlList) sortedGroups() []ACLGroupType {
+	groups := make([]ACLGroupType, 0, len(r.GroupMap))
+	for g := range r.GroupMap {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+	return groups
+}
+
+const (
+	s3XMLNS  = "http://s3.amazonaws.com/doc/2006-03-01/"
+	xsiXMLNS = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+type s3AccessControlPolicy struct {
+	XMLName           xml.Name `xml:"AccessControlPolicy"`
+	XMLNS             string   `xml:"xmlns,attr"`
+	Owner             *s3Owner `xml:"Owner,omitempty"`
+	AccessControlList struct {
+		Grants []s3Grant `xml:"Grant"`
+	} `xml:"AccessControlList"`
+}
+
+type s3Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName,omitempty"`
+}
+
+type s3Grant struct {
+	Grantee     s3Grantee `xml:"Grantee"`
+	Permissions []string  `xml:"Permission"`
+}
+
+type s3Grantee struct {
+	XMLNSXsi     string `xml:"xmlns:xsi,attr"`
+	Type         string `xml:"xsi:type,attr"`
+	ID           string `xml:"ID,omitempty"`
+	DisplayName  string `xml:"DisplayName,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+	URI          string `xml:"URI,omitempty"`
+}
+
+// MarshalXML marshals the policy as the S3 AccessControlPolicy document, like
+// RGWAccessControlPolicy_S3::to_xml. As in RGW, referer grants and grants
+// without S3 permissions are left out, and a grant that is not
+// FULL_CONTROL lists each of its permissions.
+func (r RGWAccessControlPolicy) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	p := s3AccessControlPolicy{XMLNS: s3XMLNS}
+	if id := r.Owner.ID.String(); id != "" {
+		p.Owner = &s3Owner{ID: id, DisplayName: r.Owner.DisplayName}
+	}
+	for _, entry := range r.ACL.GrantMap {
+		g := entry.Grant
+		perms := s3Permissions(g.Permission)
+		if len(perms) == 0 {
+			continue
+		}
+		grantee := s3Grantee{XMLNSXsi: xsiXMLNS}
+		switch g.Type {
+		case ACLTypeCanonUser:
+			grantee.Type = "CanonicalUser"
+			grantee.ID = g.ID.String()
+			grantee.DisplayName = g.Name
+		case ACLTypeEmailUser:
+			grantee.Type = "AmazonCustomerByEmail"
+			grantee.EmailAddress = g.Email
+		case ACLTypeGroup:
+			grantee.Type = "Group"
+			grantee.URI = g.Group.URI()
+		default:
+			continue
+		}
+		p.AccessControlList.Grants = append(p.AccessControlList.Grants, s3Grant{Grantee: grantee, Permissions: perms})
+	}
+	return e.Encode(p)
+}
+
+func s3Permissions(flags int32) []string {
+	if flags&PermFullControl == PermFullControl {
+		return []string{"FULL_CONTROL"}
+	}
+	var perms []string
+	for _, p := range []struct {
+		flag int32
+		s    string
+	}{
+		{PermRead, "READ"},
+		{PermWrite, "WRITE"},
+		{PermReadACP, "READ_ACP"},
+		{PermWriteACP, "WRITE_ACP"},
+	} {
+		if flags&p.flag != 0 {
+			perms = append(perms, p.s)
+		}
+	}
+	return perms
+}
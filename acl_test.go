@@ -0,0 +1,149 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testACLPolicy() *RGWAccessControlPolicy {
+	return &RGWAccessControlPolicy{
+		ACL: RGWAccessControlList{
+			UserMap: map[string]int32{
+				"tenant$owner":       PermFullControl,
+				"reader@example.com": PermRead,
+			},
+			GroupMap: map[ACLGroupType]int32{
+				ACLGroupAllUsers:           PermRead,
+				ACLGroupAuthenticatedUsers: PermRead | PermReadACP,
+			},
+			GrantMap: []ACLGrantEntry{
+				{Key: "", Grant: ACLGrant{Type: ACLTypeGroup, Permission: PermRead, Group: ACLGroupAllUsers}},
+				{Key: "", Grant: ACLGrant{Type: ACLTypeGroup, Permission: PermRead | PermReadACP, Group: ACLGroupAuthenticatedUsers}},
+				{Key: "", Grant: ACLGrant{Type: ACLTypeReferer, Permission: PermRead, URLSpec: ".example.com"}},
+				{Key: "reader@example.com", Grant: ACLGrant{Type: ACLTypeEmailUser, Email: "reader@example.com", Permission: PermRead}},
+				{Key: "tenant$owner", Grant: ACLGrant{Type: ACLTypeCanonUser, ID: RGWUser{Tenant: "tenant", ID: "owner"}, Permission: PermFullControl, Name: "Owner"}},
+			},
+			RefererList: []ACLReferer{{URLSpec: ".example.com", Perm: PermRead}},
+		},
+		Owner: ACLOwner{ID: RGWUser{Tenant: "tenant", ID: "owner"}, DisplayName: "Owner"},
+	}
+}
+
+func TestEncodeDecodeRGWAccessControlPolicy(t *testing.T) {
+	expected := testACLPolicy()
+	data := EncodeRGWAccessControlPolicy(expected)
+	actual, err := DecodeRGWAccessControlPolicy(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWAccessControlPolicy(actual))
+
+	out, err := json.Marshal(&RGWAccessControlPolicy{
+		ACL: RGWAccessControlList{
+			UserMap:  map[string]int32{"owner": PermFullControl},
+			GrantMap: []ACLGrantEntry{{Key: "owner", Grant: ACLGrant{ID: RGWUser{ID: "owner"}, Permission: PermFullControl, Name: "Owner"}}},
+		},
+		Owner: ACLOwner{ID: RGWUser{ID: "owner"}, DisplayName: "Owner"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"acl": {
+			"acl_user_map": [{"user": "owner", "acl": 15}],
+			"acl_group_map": [],
+			"grant_map": [{"id": "owner", "grant": {
+				"type": {"type": 0},
+				"id": "owner",
+				"email": "",
+				"permission": {"flags": 15},
+				"name": "Owner",
+				"group": 0,
+				"url_spec": ""
+			}}]
+		},
+		"owner": {"id": "owner", "display_name": "Owner"}
+	}`, string(out))
+}
+
+func TestDecodeRGWAccessControlListV1(t *testing.T) {
+	// A v1 list written without its maps, with a v1 group grant that only
+	// has the group URI. Legacy v1 structs only have the version byte.
+	e := NewEncoder()
+	e.EncodeU8(1)
+	e.EncodeBool(false)
+	e.EncodeU32(0)
+	e.EncodeU32(3)
+	e.EncodeString("")
+	e.EncodeU8(1)
+	e.EncodeACLGranteeType(ACLTypeGroup)
+	e.EncodeString("")
+	e.EncodeString(GroupURIAllUsers)
+	e.EncodeString("")
+	e.EncodeACLPermission(PermRead)
+	e.EncodeString("")
+	e.EncodeString("user")
+	e.EncodeU8(1)
+	e.EncodeACLGranteeType(ACLTypeCanonUser)
+	e.EncodeString("user")
+	e.EncodeString("")
+	e.EncodeString("")
+	e.EncodeACLPermission(PermWrite)
+	e.EncodeString("User")
+	e.EncodeString("reader@example.com")
+	e.EncodeU8(1)
+	e.EncodeACLGranteeType(ACLTypeEmailUser)
+	e.EncodeString("")
+	e.EncodeString("")
+	e.EncodeString("reader@example.com")
+	e.EncodeACLPermission(PermRead)
+	e.EncodeString("")
+
+	d := NewDecoder(e.Bytes(), DecodeOptions{ErrorOnLeftover: true})
+	acl, err := d.DecodeRGWAccessControlList()
+	assert.NoError(t, err)
+	assert.NoError(t, d.Done())
+	assert.Equal(t, map[string]int32{"user": PermWrite, "reader@example.com": PermRead}, acl.UserMap)
+	assert.Equal(t, map[ACLGroupType]int32{ACLGroupAllUsers: PermRead}, acl.GroupMap)
+	assert.Equal(t, ACLGroupAllUsers, acl.GrantMap[0].Grant.Group)
+}
+
+func TestRGWAccessControlPolicyXML(t *testing.T) {
+	out, err := xml.MarshalIndent(testACLPolicy(), "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, `<AccessControlPolicy xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Owner>
+    <ID>tenant$owner</ID>
+    <DisplayName>Owner</DisplayName>
+  </Owner>
+  <AccessControlList>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group">
+        <URI>http://acs.amazonaws.com/groups/global/AllUsers</URI>
+      </Grantee>
+      <Permission>READ</Permission>
+    </Grant>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group">
+        <URI>http://acs.amazonaws.com/groups/global/AuthenticatedUsers</URI>
+      </Grantee>
+      <Permission>READ</Permission>
+      <Permission>READ_ACP</Permission>
+    </Grant>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="AmazonCustomerByEmail">
+        <EmailAddress>reader@example.com</EmailAddress>
+      </Grantee>
+      <Permission>READ</Permission>
+    </Grant>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser">
+        <ID>tenant$owner</ID>
+        <DisplayName>Owner</DisplayName>
+      </Grantee>
+      <Permission>FULL_CONTROL</Permission>
+    </Grant>
+  </AccessControlList>
+</AccessControlPolicy>`, string(out))
+}
@@ -0,0 +1,151 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// benchManifest returns an explicit manifest of a multipart upload with n
+// parts, all in the same bucket like they are in practice.
+func benchManifest(n int) []byte {
+	bucket := RGWBucket{
+		Name:     "bucket",
+		Marker:   "d29b7d7b-87c7-480e-b614-3006673b3f18.56565235.42459",
+		BucketID: "d29b7d7b-87c7-480e-b614-3006673b3f18.56565235.42459",
+	}
+	m := &RGWObjManifest{
+		ExplicitObjs: true,
+		ObjSize:      uint64(n) << 22,
+		Prefix:       "9c9fa8e1dda44c0c97f3e712b3d5f30d.jpg.2~Pmszle3PRpb_Olh6RT147s5stV24lCA",
+		Objs:         make(map[uint64]RGWObjManifestPart, n),
+		Rules:        map[uint64]RGWObjManifestRule{0: {StartPartNum: 1, PartSize: 4 << 20, StripeMaxSize: 4 << 20}},
+		Obj: RGWObj{
+			Bucket: bucket,
+			Key:    RGWObjKey{Name: "9c9fa8e1dda44c0c97f3e712b3d5f30d.jpg"},
+		},
+		TailPlacement:     RGWBucketPlacement{Bucket: bucket},
+		HeadPlacementRule: RGWPlacementRule{Name: "default-placement"},
+	}
+	for i := 0; i < n; i++ {
+		m.Objs[uint64(i)<<22] = RGWObjManifestPart{
+			Loc: RGWObj{
+				Bucket: bucket,
+				Key: RGWObjKey{
+					Name: fmt.Sprintf("%s.%d", m.Prefix, i+1),
+					NS:   multipart,
+				},
+			},
+			Size: 4 << 20,
+		}
+	}
+	return EncodeRGWObjManifest(m)
+}
+
+// benchBucketEntries returns n concatenated cls_user_bucket_entry records.
+func benchBucketEntries(n int) []byte {
+	e := NewEncoder()
+	for i := 0; i < n; i++ {
+		e.EncodeUserBucketEntry(&UserBucketEntry{
+			Size:         uint64(i) << 10,
+			SizeRounded:  uint64(i+1) << 12,
+			CreationTime: time.Unix(1600000000+int64(i), 0),
+			Count:        uint64(i),
+			Bucket: UserBucket{
+				Name:        fmt.Sprintf("bucket-%d", i),
+				Marker:      fmt.Sprintf("d29b7d7b-87c7-480e-b614-3006673b3f18.56565235.%d", i),
+				BucketID:    fmt.Sprintf("d29b7d7b-87c7-480e-b614-3006673b3f18.56565235.%d", i),
+				PlacementID: "default-placement",
+			},
+		})
+	}
+	return e.Bytes()
+}
+
+func BenchmarkDecodeU64(b *testing.B) {
+	data := make([]byte, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := Decoder{Data: data}
+		if _, err := d.DecodeU64(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRGWObjManifest(b *testing.B) {
+	for _, n := range []int{1, 100, 1000} {
+		data := benchManifest(n)
+		b.Run(fmt.Sprintf("parts=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeRGWObjManifest(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("parts=%d/intern", n), func(b *testing.B) {
+			strings := NewStringTable(1024)
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeRGWObjManifest(data, DecodeOptions{Strings: strings}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeRGWObjManifestTestdata(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/manifest_2")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeRGWObjManifest(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeUserBucketEntry(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeUserBucketEntry(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReaderUserBucketEntries(b *testing.B) {
+	data := benchBucketEntries(10000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(data), FramingUserBucketEntry)
+		for {
+			d, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := d.DecodeUserBucketEntry(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,476 @@
+package decoder
+
+import (
+	"strings"
+	"time"
+)
+
+// biPrefixChar starts the keys of the special bucket index namespaces.
+const biPrefixChar = "\x80"
+
+// Key prefixes of the special bucket index namespaces, after biPrefixChar.
+const (
+	biLogPrefix      = "0_"
+	biInstancePrefix = "1000_"
+	biOLHPrefix      = "1001_"
+)
+
+// Bilog flags of RGWBILogEntry.
+const (
+	BILogFlagVersionedOp = 0x1
+)
+
+// BIIndexType is a BIIndexType, the namespace of a bucket index key.
+// BILogIdx is not a BIIndexType of Ceph, whose bi list skips the bucket index
+// log.
+type BIIndexType uint8
+
+const (
+	BIInvalidIdx BIIndexType = iota
+	BIPlainIdx
+	BIInstanceIdx
+	BIOLHIdx
+	BILogIdx
+)
+
+func (t BIIndexType) String() string {
+	switch t {
+	case BIPlainIdx:
+		return "plain"
+	case BIInstanceIdx:
+		return "instance"
+	case BIOLHIdx:
+		return "olh"
+	case BILogIdx:
+		return "log"
+	}
+	return "invalid"
+}
+
+// OLHLogOp is a OLHLogOp, the operation of a rgw_bucket_olh_log_entry.
+type OLHLogOp uint8
+
+const (
+	OLHOpUnknown OLHLogOp = iota
+	OLHOpLinkOLH
+	OLHOpUnlinkOLH
+	OLHOpRemoveInstance
+)
+
+func (o OLHLogOp) String() string {
+	switch o {
+	case OLHOpLinkOLH:
+		return "link_olh"
+	case OLHOpUnlinkOLH:
+		return "unlink_olh"
+	case OLHOpRemoveInstance:
+		return "remove_instance"
+	}
+	return "unknown"
+}
+
+// BIKey is a bucket index omap key split into its parts. Plain keys of
+// versioned objects and instance keys carry the object's instance; LogID is
+// the id of a bucket index log entry.
+type BIKey struct {
+	Type         BIIndexType
+	Name         string
+	Instance     string
+	DeleteMarker bool
+	LogID        string
+}
+
+// ParseBIKey classifies a raw bucket index omap key. Keys starting with
+// biPrefixChar but in none of the known namespaces are BIInvalidIdx.
+func ParseBIKey(key string) BIKey {
+	if !strings.HasPrefix(key, biPrefixChar) {
+		k := BIKey{Type: BIPlainIdx}
+		k.splitName(key)
+		return k
+	}
+	rest := key[len(biPrefixChar):]
+	switch {
+	case strings.HasPrefix(rest, biLogPrefix):
+		return BIKey{Type: BILogIdx, LogID: rest[len(biLogPrefix):]}
+	case strings.HasPrefix(rest, biInstancePrefix):
+		k := BIKey{Type: BIInstanceIdx}
+		k.splitName(rest[len(biInstancePrefix):])
+		return k
+	case strings.HasPrefix(rest, biOLHPrefix):
+		return BIKey{Type: BIOLHIdx, Name: rest[len(biOLHPrefix):]}
+	}
+	return BIKey{Type: BIInvalidIdx}
+}
+
+// splitName splits "name\0v<ver>\0i<instance>\0d" into its parts; object
+// names cannot contain a NUL.
+func (k *BIKey) splitName(s string) {
+	parts := strings.Split(s, "\x00")
+	k.Name = parts[0]
+	for _, p := range parts[1:] {
+		switch {
+		case strings.HasPrefix(p, "i"):
+			k.Instance = p[1:]
+		case p == "d":
+			k.DeleteMarker = true
+		}
+	}
+}
+
+// RGWBucketOLHLogEntry is a rgw_bucket_olh_log_entry.
+type RGWBucketOLHLogEntry struct {
+	Epoch        uint64
+	Op           OLHLogOp
+	OpTag        string
+	Key          ClsRGWObjKey
+	DeleteMarker bool
+}
+
+// RGWBucketOLHPendingLog is an entry of the pending_log map of a
+// rgw_bucket_olh_entry.
+type RGWBucketOLHPendingLog struct {
+	Epoch   uint64
+	Entries []RGWBucketOLHLogEntry
+}
+
+// RGWBucketOLHEntry is a rgw_bucket_olh_entry, the value of an OLH key that
+// points to the current instance of a versioned object.
+type RGWBucketOLHEntry struct {
+	Key            ClsRGWObjKey
+	DeleteMarker   bool
+	Epoch          uint64
+	PendingLog     []RGWBucketOLHPendingLog
+	Tag            string
+	Exists         bool
+	PendingRemoval bool
+}
+
+// RGWBILogEntry is a rgw_bi_log_entry, an entry of the bucket index log
+// multisite sync replays.
+type RGWBILogEntry struct {
+	ID               string
+	Object           string
+	Instance         string
+	Timestamp        time.Time
+	Ver              RGWBucketEntryVer
+	Op               RGWModifyOp
+	State            RGWPendingState
+	IndexVer         uint64
+	Tag              string
+	BILogFlags       uint16
+	Owner            string
+	OwnerDisplayName string
+	ZonesTrace       []string
+}
+
+// BIEntry is a bucket index entry of any namespace, as listed by
+// `radosgw-admin bi list`. Entry is a *RGWBucketDirEntry for plain and
+// instance keys, a *RGWBucketOLHEntry for OLH keys and a *RGWBILogEntry for
+// bucket index log keys.
+type BIEntry struct {
+	Type  BIIndexType
+	Idx   string
+	Entry interface{}
+}
+
+// DecodeBIEntry decodes the omap value data of the bucket index key idx.
+func DecodeBIEntry(idx string, data []byte, opts ...DecodeOptions) (*BIEntry, error) {
+	d := NewDecoder(data, opts...)
+	e, err := d.DecodeBIEntry(idx)
+	if err != nil {
+		return nil, err
+	}
+	return e, d.Done()
+}
+
+func DecodeRGWBucketOLHEntry(data []byte, opts ...DecodeOptions) (*RGWBucketOLHEntry, error) {
+	d := NewDecoder(data, opts...)
+	e, err := d.DecodeRGWBucketOLHEntry()
+	if err != nil {
+		return nil, err
+	}
+	return e, d.Done()
+}
+
+func DecodeRGWBILogEntry(data []byte, opts ...DecodeOptions) (*RGWBILogEntry, error) {
+	d := NewDecoder(data, opts...)
+	e, err := d.DecodeRGWBILogEntry()
+	if err != nil {
+		return nil, err
+	}
+	return e, d.Done()
+}
+
+// DecodeBIEntry decodes the omap value of the bucket index key idx with the
+// decoder of its namespace. Values of invalid keys fail with
+// ErrUnknownType.
+func (d *Decoder) DecodeBIEntry(idx string) (*BIEntry, error) {
+	r := BIEntry{Type: ParseBIKey(idx).Type, Idx: idx}
+	var err error
+	switch r.Type {
+	case BIPlainIdx, BIInstanceIdx:
+		r.Entry, err = d.DecodeRGWBucketDirEntry()
+	case BIOLHIdx:
+		r.Entry, err = d.DecodeRGWBucketOLHEntry()
+	case BILogIdx:
+		r.Entry, err = d.DecodeRGWBILogEntry()
+	default:
+		err = d.NewError(ErrUnknownType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// DecodeClsRGWObjKey decodes a cls_rgw_obj_key.
+func (d *Decoder) DecodeClsRGWObjKey() (*ClsRGWObjKey, error) {
+	var r ClsRGWObjKey
+	_, _, structEnd, err := d.DecodeStart("cls_rgw_obj_key", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("name")
+	if r.Name, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("instance")
+	if r.Instance, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketOLHLogEntry decodes a rgw_bucket_olh_log_entry.
+func (d *Decoder) DecodeRGWBucketOLHLogEntry() (*RGWBucketOLHLogEntry, error) {
+	var r RGWBucketOLHLogEntry
+	_, _, structEnd, err := d.DecodeStart("rgw_bucket_olh_log_entry", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("epoch")
+	if r.Epoch, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("op")
+	op, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Op = OLHLogOp(op)
+	d.Field("op_tag")
+	if r.OpTag, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("key")
+	key, err := d.DecodeClsRGWObjKey()
+	if err != nil {
+		return nil, err
+	}
+	r.Key = *key
+	d.Field("delete_marker")
+	if r.DeleteMarker, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketOLHEntry decodes a rgw_bucket_olh_entry.
+func (d *Decoder) DecodeRGWBucketOLHEntry() (*RGWBucketOLHEntry, error) {
+	var r RGWBucketOLHEntry
+	_, _, structEnd, err := d.DecodeStart("rgw_bucket_olh_entry", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("key")
+	key, err := d.DecodeClsRGWObjKey()
+	if err != nil {
+		return nil, err
+	}
+	r.Key = *key
+	d.Field("delete_marker")
+	if r.DeleteMarker, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	d.Field("epoch")
+	if r.Epoch, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	err = d.DecodeMap("pending_log", func(i uint32) error {
+		var l RGWBucketOLHPendingLog
+		epoch, err := d.DecodeU64()
+		if err != nil {
+			return err
+		}
+		l.Epoch = epoch
+		err = d.DecodeList("entries", func(j uint32) error {
+			e, err := d.DecodeRGWBucketOLHLogEntry()
+			if err != nil {
+				return err
+			}
+			l.Entries = append(l.Entries, *e)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.PendingLog = append(r.PendingLog, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Field("tag")
+	if r.Tag, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("exists")
+	if r.Exists, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	d.Field("pending_removal")
+	if r.PendingRemoval, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBILogEntry decodes a rgw_bi_log_entry.
+func (d *Decoder) DecodeRGWBILogEntry() (*RGWBILogEntry, error) {
+	var r RGWBILogEntry
+	structV, _, structEnd, err := d.DecodeStart("rgw_bi_log_entry", 4)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("id")
+	if r.ID, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("object")
+	if r.Object, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("timestamp")
+	if r.Timestamp, err = d.DecodeRealTime(); err != nil {
+		return nil, err
+	}
+	d.Field("ver")
+	ver, err := d.DecodeRGWBucketEntryVer()
+	if err != nil {
+		return nil, err
+	}
+	r.Ver = *ver
+	d.Field("tag")
+	if r.Tag, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("op")
+	op, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Op = RGWModifyOp(op)
+	d.Field("state")
+	state, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.State = RGWPendingState(state)
+	d.Field("index_ver")
+	if r.IndexVer, err = d.DecodePackedVal(); err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("instance")
+		if r.Instance, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+		d.Field("bilog_flags")
+		if r.BILogFlags, err = d.DecodeU16(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 3 {
+		d.Field("owner")
+		if r.Owner, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+		d.Field("owner_display_name")
+		if r.OwnerDisplayName, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		if r.ZonesTrace, err = d.DecodeStringSet("zones_trace"); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeClsRGWObjKey(r *ClsRGWObjKey) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeString(r.Name)
+	e.EncodeString(r.Instance)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBucketOLHLogEntry(r *RGWBucketOLHLogEntry) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU64(r.Epoch)
+	e.EncodeU8(uint8(r.Op))
+	e.EncodeString(r.OpTag)
+	e.EncodeClsRGWObjKey(&r.Key)
+	e.EncodeBool(r.DeleteMarker)
+	e.EncodeFinish(start)
+}
+
+// EncodeRGWBucketOLHEntry encodes r. PendingLog must be sorted by epoch like
+// the std::map it stands for.
+func (e *Encoder) EncodeRGWBucketOLHEntry(r *RGWBucketOLHEntry) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeClsRGWObjKey(&r.Key)
+	e.EncodeBool(r.DeleteMarker)
+	e.EncodeU64(r.Epoch)
+	e.EncodeList(len(r.PendingLog), func(i int) {
+		l := r.PendingLog[i]
+		e.EncodeU64(l.Epoch)
+		e.EncodeList(len(l.Entries), func(j int) {
+			e.EncodeRGWBucketOLHLogEntry(&l.Entries[j])
+		})
+	})
+	e.EncodeString(r.Tag)
+	e.EncodeBool(r.Exists)
+	e.EncodeBool(r.PendingRemoval)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBILogEntry(r *RGWBILogEntry) {
+	start := e.EncodeStart(4, 1)
+	e.EncodeString(r.ID)
+	e.EncodeString(r.Object)
+	e.EncodeRealTime(r.Timestamp)
+	e.EncodeRGWBucketEntryVer(&r.Ver)
+	e.EncodeString(r.Tag)
+	e.EncodeU8(uint8(r.Op))
+	e.EncodeU8(uint8(r.State))
+	e.EncodePackedVal(r.IndexVer)
+	e.EncodeString(r.Instance)
+	e.EncodeU16(r.BILogFlags)
+	e.EncodeString(r.Owner)
+	e.EncodeString(r.OwnerDisplayName)
+	e.EncodeStringList(r.ZonesTrace)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWBucketOLHEntry(r *RGWBucketOLHEntry) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucketOLHEntry(r)
+	return e.Bytes()
+}
+
+func EncodeRGWBILogEntry(r *RGWBILogEntry) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBILogEntry(r)
+	return e.Bytes()
+}
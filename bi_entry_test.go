@@ -0,0 +1,175 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBIKey(t *testing.T) {
+	testcases := []struct {
+		name     string
+		key      string
+		expected BIKey
+	}{
+		{"plain", "photos/cat.jpg", BIKey{Type: BIPlainIdx, Name: "photos/cat.jpg"}},
+		{"plain versioned", "cat.jpg\x00v913\x00iXgfYa", BIKey{Type: BIPlainIdx, Name: "cat.jpg", Instance: "XgfYa"}},
+		{"instance", "\x801000_cat.jpg\x00iXgfYa", BIKey{Type: BIInstanceIdx, Name: "cat.jpg", Instance: "XgfYa"}},
+		{"instance delete marker", "\x801000_cat.jpg\x00iXgfYa\x00d", BIKey{Type: BIInstanceIdx, Name: "cat.jpg", Instance: "XgfYa", DeleteMarker: true}},
+		{"olh", "\x801001_cat.jpg", BIKey{Type: BIOLHIdx, Name: "cat.jpg"}},
+		{"log", "\x800_00000000017.17.5", BIKey{Type: BILogIdx, LogID: "00000000017.17.5"}},
+		{"invalid", "\x809999_", BIKey{Type: BIInvalidIdx}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseBIKey(tt.key))
+		})
+	}
+}
+
+func TestEncodeDecodeRGWBucketOLHEntry(t *testing.T) {
+	expected := &RGWBucketOLHEntry{
+		Key:   ClsRGWObjKey{Name: "cat.jpg", Instance: "XgfYa"},
+		Epoch: 3,
+		PendingLog: []RGWBucketOLHPendingLog{
+			{Epoch: 2, Entries: []RGWBucketOLHLogEntry{
+				{Epoch: 2, Op: OLHOpLinkOLH, OpTag: "tag1", Key: ClsRGWObjKey{Name: "cat.jpg", Instance: "XgfYa"}},
+				{Epoch: 2, Op: OLHOpRemoveInstance, OpTag: "tag2", Key: ClsRGWObjKey{Name: "cat.jpg", Instance: "old"}, DeleteMarker: true},
+			}},
+		},
+		Tag:    "olhtag",
+		Exists: true,
+	}
+	data := EncodeRGWBucketOLHEntry(expected)
+	actual, err := DecodeRGWBucketOLHEntry(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	out, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"key": {"name": "cat.jpg", "instance": "XgfYa"},
+		"delete_marker": false,
+		"epoch": 3,
+		"pending_log": [{"key": 2, "val": [
+			{"epoch": 2, "op": "link_olh", "op_tag": "tag1", "key": {"name": "cat.jpg", "instance": "XgfYa"}, "delete_marker": false},
+			{"epoch": 2, "op": "remove_instance", "op_tag": "tag2", "key": {"name": "cat.jpg", "instance": "old"}, "delete_marker": true}
+		]}],
+		"tag": "olhtag",
+		"exists": true,
+		"pending_removal": false
+	}`, string(out))
+}
+
+func TestEncodeDecodeRGWBILogEntry(t *testing.T) {
+	expected := &RGWBILogEntry{
+		ID:               "00000000017.17.5",
+		Object:           "cat.jpg",
+		Instance:         "XgfYa",
+		Timestamp:        time.Unix(1600000000, 5).UTC(),
+		Ver:              RGWBucketEntryVer{Pool: 7, Epoch: 42},
+		Op:               OpLinkOLHDM,
+		State:            PendingStateComplete,
+		IndexVer:         17,
+		Tag:              "zone.4217.123",
+		BILogFlags:       BILogFlagVersionedOp,
+		Owner:            "user",
+		OwnerDisplayName: "User",
+		ZonesTrace:       []string{"zone-a", "zone-b"},
+	}
+	data := EncodeRGWBILogEntry(expected)
+	actual, err := DecodeRGWBILogEntry(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWBILogEntry(actual))
+
+	out, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"op_id": "00000000017.17.5",
+		"op_tag": "zone.4217.123",
+		"op": "link_olh_del",
+		"object": "cat.jpg",
+		"instance": "XgfYa",
+		"state": "complete",
+		"index_ver": 17,
+		"timestamp": "2020-09-13T12:26:40.000000005Z",
+		"ver": {"pool": 7, "epoch": 42},
+		"bilog_flags": 1,
+		"versioned": true,
+		"owner": "user",
+		"owner_display_name": "User",
+		"zones_trace": [{"entry": "zone-a"}, {"entry": "zone-b"}]
+	}`, string(out))
+}
+
+func TestDecodeRGWBILogEntryV1(t *testing.T) {
+	e := NewEncoder()
+	start := e.EncodeStart(1, 1)
+	e.EncodeString("00000000001.3.2")
+	e.EncodeString("obj")
+	e.EncodeTime(1400000000, 0)
+	e.EncodeRGWBucketEntryVer(&RGWBucketEntryVer{Pool: 1, Epoch: 3})
+	e.EncodeString("tag")
+	e.EncodeU8(uint8(OpDel))
+	e.EncodeU8(uint8(PendingStateModify))
+	e.EncodePackedVal(1)
+	e.EncodeFinish(start)
+
+	entry, err := DecodeRGWBILogEntry(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, &RGWBILogEntry{
+		ID:        "00000000001.3.2",
+		Object:    "obj",
+		Timestamp: time.Unix(1400000000, 0).UTC(),
+		Ver:       RGWBucketEntryVer{Pool: 1, Epoch: 3},
+		Op:        OpDel,
+		State:     PendingStateModify,
+		IndexVer:  1,
+		Tag:       "tag",
+	}, entry)
+}
+
+func TestDecodeBIEntry(t *testing.T) {
+	olh := &RGWBucketOLHEntry{Key: ClsRGWObjKey{Name: "cat.jpg", Instance: "XgfYa"}, Epoch: 2, Exists: true}
+	dir := &RGWBucketDirEntry{Key: ClsRGWObjKey{Name: "cat.jpg", Instance: "XgfYa"}, Exists: true}
+	bilog := &RGWBILogEntry{ID: "00000000017.17.5", Object: "cat.jpg"}
+
+	e, err := DecodeBIEntry("\x801001_cat.jpg", EncodeRGWBucketOLHEntry(olh))
+	assert.NoError(t, err)
+	assert.Equal(t, &BIEntry{Type: BIOLHIdx, Idx: "\x801001_cat.jpg", Entry: olh}, e)
+
+	out, err := json.Marshal(e)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "olh",
+		"idx": "\u00801001_cat.jpg",
+		"entry": {
+			"key": {"name": "cat.jpg", "instance": "XgfYa"},
+			"delete_marker": false,
+			"epoch": 2,
+			"pending_log": [],
+			"tag": "",
+			"exists": true,
+			"pending_removal": false
+		}
+	}`, string(out))
+
+	for _, key := range []string{"cat.jpg\x00v913\x00iXgfYa", "\x801000_cat.jpg\x00iXgfYa"} {
+		e, err = DecodeBIEntry(key, EncodeRGWBucketDirEntry(dir))
+		assert.NoError(t, err)
+		assert.Equal(t, dir, e.Entry)
+	}
+
+	e, err = DecodeBIEntry("\x800_00000000017.17.5", EncodeRGWBILogEntry(bilog))
+	assert.NoError(t, err)
+	assert.Equal(t, BILogIdx, e.Type)
+	assert.Equal(t, bilog, e.Entry)
+
+	_, err = DecodeBIEntry("\x809999_", []byte{})
+	assert.True(t, errors.Is(err, ErrUnknownType))
+}
@@ -0,0 +1,207 @@
+package decoder
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	entryPointNoBucketInfoV = 8
+	entryPointOwnerV        = 9
+	entryPointRealTimeV     = 10
+	entryPointMaxSupportedV = 10
+)
+
+// RGWBucketEntryPoint is a RGWBucketEntryPoint, the content of the bucket
+// entrypoint object <tenant>/<bucket> in the root pool that links a bucket
+// name to its current instance.
+//
+// Encodings before v8 are a whole RGWBucketInfo; they are decoded into
+// OldBucketInfo with HasBucketInfo set, and Bucket, Owner and CreationTime are
+// copied from it like Ceph does.
+type RGWBucketEntryPoint struct {
+	Bucket        RGWBucket
+	Owner         RGWUser
+	CreationTime  time.Time
+	Linked        bool
+	HasBucketInfo bool
+	OldBucketInfo *RGWBucketInfo
+}
+
+// ObjVersion is a obj_version, the version of a metadata object kept by
+// cls_version in its ceph.objclass.version xattr.
+type ObjVersion struct {
+	Ver uint64
+	Tag string
+}
+
+// CheckUserBucket returns an error wrapping ErrBucketMismatch if the bucket
+// u of a user's bucket list is not the instance the entrypoint links to.
+func (e *RGWBucketEntryPoint) CheckUserBucket(u *UserBucket) error {
+	switch {
+	case u.Name != e.Bucket.Name:
+		return fmt.Errorf("%w: name %q, entrypoint has %q", ErrBucketMismatch, u.Name, e.Bucket.Name)
+	case u.Marker != e.Bucket.Marker:
+		return fmt.Errorf("%w: marker %q, entrypoint has %q", ErrBucketMismatch, u.Marker, e.Bucket.Marker)
+	case u.BucketID != e.Bucket.BucketID:
+		return fmt.Errorf("%w: bucket_id %q, entrypoint has %q", ErrBucketMismatch, u.BucketID, e.Bucket.BucketID)
+	}
+	return nil
+}
+
+func DecodeRGWBucketEntryPoint(data []byte, opts ...DecodeOptions) (*RGWBucketEntryPoint, error) {
+	d := NewDecoder(data, opts...)
+	e, err := d.DecodeRGWBucketEntryPoint()
+	if err != nil {
+		return nil, err
+	}
+	return e, d.Done()
+}
+
+func DecodeObjVersion(data []byte, opts ...DecodeOptions) (*ObjVersion, error) {
+	d := NewDecoder(data, opts...)
+	v, err := d.DecodeObjVersion()
+	if err != nil {
+		return nil, err
+	}
+	return v, d.Done()
+}
+
+// DecodeRGWBucketEntryPoint decodes a RGWBucketEntryPoint.
+func (d *Decoder) DecodeRGWBucketEntryPoint() (*RGWBucketEntryPoint, error) {
+	var r RGWBucketEntryPoint
+	if d.Remaining() > 0 && d.Data[d.Offset] < entryPointNoBucketInfoV {
+		// An old entrypoint is the bucket info itself.
+		info, err := d.DecodeRGWBucketInfo()
+		if err != nil {
+			return nil, err
+		}
+		r.HasBucketInfo = true
+		r.OldBucketInfo = info
+		r.Bucket = info.Bucket
+		r.Owner = info.Owner
+		r.CreationTime = info.CreationTime
+		return &r, nil
+	}
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen32("RGWBucketEntryPoint", entryPointMaxSupportedV, 4, 4)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("bucket")
+	bucket, err := d.DecodeRGWBucket()
+	if err != nil {
+		return nil, err
+	}
+	r.Bucket = *bucket
+	d.Field("owner.id")
+	if r.Owner.ID, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("linked")
+	if r.Linked, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	d.Field("creation_time")
+	ct, err := d.DecodeU64()
+	if err != nil {
+		return nil, err
+	}
+	if structV < entryPointRealTimeV && ct != 0 {
+		r.CreationTime = time.Unix(int64(ct), 0).UTC()
+	}
+	if structV >= entryPointOwnerV {
+		d.Field("owner")
+		owner, err := d.DecodeRGWUser()
+		if err != nil {
+			return nil, err
+		}
+		r.Owner = *owner
+	}
+	if structV >= entryPointRealTimeV {
+		d.Field("creation_time")
+		if r.CreationTime, err = d.DecodeRealTime(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWUser decodes a rgw_user. Its namespace is not part of the
+// encoding.
+func (d *Decoder) DecodeRGWUser() (*RGWUser, error) {
+	var r RGWUser
+	_, _, structEnd, err := d.DecodeStart("rgw_user", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("tenant")
+	if r.Tenant, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("id")
+	if r.ID, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeObjVersion decodes a obj_version.
+func (d *Decoder) DecodeObjVersion() (*ObjVersion, error) {
+	var r ObjVersion
+	_, _, structEnd, err := d.DecodeStart("obj_version", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("ver")
+	if r.Ver, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("tag")
+	if r.Tag, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeRGWUser(r *RGWUser) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeString(r.Tenant)
+	e.EncodeString(r.ID)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeObjVersion(r *ObjVersion) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU64(r.Ver)
+	e.EncodeString(r.Tag)
+	e.EncodeFinish(start)
+}
+
+// EncodeRGWBucketEntryPoint encodes r in the v10 format. Like Ceph it never
+// writes the old bucket info.
+func (e *Encoder) EncodeRGWBucketEntryPoint(r *RGWBucketEntryPoint) {
+	start := e.EncodeStart(entryPointMaxSupportedV, 8)
+	e.EncodeRGWBucket(&r.Bucket)
+	e.EncodeString(r.Owner.ID)
+	e.EncodeBool(r.Linked)
+	var ct uint64
+	if !r.CreationTime.IsZero() {
+		ct = uint64(r.CreationTime.Unix())
+	}
+	e.EncodeU64(ct)
+	e.EncodeRGWUser(&r.Owner)
+	e.EncodeRealTime(r.CreationTime)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWBucketEntryPoint(r *RGWBucketEntryPoint) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucketEntryPoint(r)
+	return e.Bytes()
+}
+
+func EncodeObjVersion(r *ObjVersion) []byte {
+	e := NewEncoder()
+	e.EncodeObjVersion(r)
+	return e.Bytes()
+}
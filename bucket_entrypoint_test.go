@@ -0,0 +1,96 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeRGWBucketEntryPoint(t *testing.T) {
+	expected := &RGWBucketEntryPoint{
+		Bucket: RGWBucket{
+			Tenant:   "tenant",
+			Name:     "bucket",
+			Marker:   "zone.1234.1",
+			BucketID: "zone.1234.2",
+		},
+		Owner:        RGWUser{Tenant: "tenant", ID: "user"},
+		CreationTime: time.Unix(1600000000, 5000).UTC(),
+		Linked:       true,
+	}
+	data := EncodeRGWBucketEntryPoint(expected)
+	actual, err := DecodeRGWBucketEntryPoint(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	out, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"bucket": {"name": "bucket", "marker": "zone.1234.1", "bucket_id": "zone.1234.2", "tenant": "tenant",
+			"explicit_placement": {"data_pool": "", "data_extra_pool": "", "index_pool": ""}},
+		"owner": "tenant$user",
+		"creation_time": "2020-09-13T12:26:40.000005Z",
+		"linked": true,
+		"has_bucket_info": false
+	}`, string(out))
+}
+
+func TestDecodeRGWBucketEntryPointV8(t *testing.T) {
+	e := NewEncoder()
+	start := e.EncodeStart(8, 8)
+	e.EncodeRGWBucket(&RGWBucket{Name: "bucket", Marker: "default.1", BucketID: "default.1"})
+	e.EncodeString("user")
+	e.EncodeBool(false)
+	e.EncodeU64(1400000000)
+	e.EncodeFinish(start)
+
+	ep, err := DecodeRGWBucketEntryPoint(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, &RGWBucketEntryPoint{
+		Bucket:       RGWBucket{Name: "bucket", Marker: "default.1", BucketID: "default.1"},
+		Owner:        RGWUser{ID: "user"},
+		CreationTime: time.Unix(1400000000, 0).UTC(),
+	}, ep)
+}
+
+func TestDecodeRGWBucketEntryPointOldBucketInfo(t *testing.T) {
+	// A v3 entrypoint is a RGWBucketInfo v3 with a u32 version and neither
+	// compat version nor length.
+	e := NewEncoder()
+	e.EncodeU32(3)
+	e.EncodeRGWBucket(&RGWBucket{Name: "bucket", Marker: "default.1", BucketID: "default.1"})
+	e.EncodeString("user")
+	e.EncodeU32(BucketSuspended)
+
+	ep, err := DecodeRGWBucketEntryPoint(e.Bytes())
+	assert.NoError(t, err)
+	assert.True(t, ep.HasBucketInfo)
+	assert.Equal(t, RGWBucket{Name: "bucket", Marker: "default.1", BucketID: "default.1"}, ep.Bucket)
+	assert.Equal(t, RGWUser{ID: "user"}, ep.Owner)
+	assert.Equal(t, uint32(BucketSuspended), ep.OldBucketInfo.Flags)
+
+	out, err := json.Marshal(ep)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `{"old_bucket_info":{"bucket":{"name":"bucket",`)
+}
+
+func TestCheckUserBucket(t *testing.T) {
+	ep := &RGWBucketEntryPoint{
+		Bucket: RGWBucket{Name: "bucket", Marker: "zone.1", BucketID: "zone.2"},
+	}
+	assert.NoError(t, ep.CheckUserBucket(&UserBucket{Name: "bucket", Marker: "zone.1", BucketID: "zone.2"}))
+	err := ep.CheckUserBucket(&UserBucket{Name: "bucket", Marker: "zone.1", BucketID: "zone.1"})
+	assert.True(t, errors.Is(err, ErrBucketMismatch))
+	assert.EqualError(t, err, `bucket does not match its entrypoint: bucket_id "zone.1", entrypoint has "zone.2"`)
+}
+
+func TestEncodeDecodeObjVersion(t *testing.T) {
+	expected := &ObjVersion{Ver: 3, Tag: "_Mk1jZ5ayTkUN0PTBKqNjK6L"}
+	actual, err := DecodeObjVersion(EncodeObjVersion(expected))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
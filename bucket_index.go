@@ -0,0 +1,724 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Flags of RGWBucketDirEntry.
+const (
+	DirEntryFlagVer          = 0x1
+	DirEntryFlagCurrent      = 0x2
+	DirEntryFlagDeleteMarker = 0x4
+	DirEntryFlagVerMarker    = 0x8
+	DirEntryFlagCommonPrefix = 0x8000
+)
+
+const (
+	dirEntryMaxSupportedV     = 8
+	dirEntryMetaMaxSupportedV = 7
+	dirHeaderMaxSupportedV    = 7
+)
+
+// RGWObjCategory is a RGWObjCategory, the category a bucket index entry is
+// accounted under.
+type RGWObjCategory uint8
+
+const (
+	RGWObjCategoryNone RGWObjCategory = iota
+	RGWObjCategoryMain
+	RGWObjCategoryShadow
+	RGWObjCategoryMultiMeta
+	RGWObjCategoryCloudTiered
+)
+
+// String returns the name of the category as rgw_obj_category_name does,
+// e.g. "rgw.main".
+func (c RGWObjCategory) String() string {
+	switch c {
+	case RGWObjCategoryNone:
+		return "rgw.none"
+	case RGWObjCategoryMain:
+		return "rgw.main"
+	case RGWObjCategoryShadow:
+		return "rgw.shadow"
+	case RGWObjCategoryMultiMeta:
+		return "rgw.multimeta"
+	case RGWObjCategoryCloudTiered:
+		return "rgw.cloudtiered"
+	}
+	return "unknown"
+}
+
+// RGWPendingState is a RGWPendingState.
+type RGWPendingState uint8
+
+const (
+	PendingStateModify RGWPendingState = iota
+	PendingStateComplete
+	PendingStateUnknown
+)
+
+func (s RGWPendingState) String() string {
+	switch s {
+	case PendingStateModify:
+		return "pending"
+	case PendingStateComplete:
+		return "complete"
+	}
+	return "invalid"
+}
+
+// RGWModifyOp is a RGWModifyOp, the operation of a pending index change or
+// bucket index log entry.
+type RGWModifyOp uint8
+
+const (
+	OpAdd RGWModifyOp = iota
+	OpDel
+	OpCancel
+	OpUnknown
+	OpLinkOLH
+	OpLinkOLHDM
+	OpUnlinkInstance
+	OpSyncStop
+	OpResync
+)
+
+// String returns the name of the operation as the bucket index log dump
+// shows it.
+func (o RGWModifyOp) String() string {
+	switch o {
+	case OpAdd:
+		return "write"
+	case OpDel:
+		return "del"
+	case OpCancel:
+		return "cancel"
+	case OpUnknown:
+		return "unknown"
+	case OpLinkOLH:
+		return "link_olh"
+	case OpLinkOLHDM:
+		return "link_olh_del"
+	case OpUnlinkInstance:
+		return "unlink_instance"
+	case OpSyncStop:
+		return "syncstop"
+	case OpResync:
+		return "resync"
+	}
+	return "invalid"
+}
+
+// ClsRGWObjKey is a cls_rgw_obj_key.
+type ClsRGWObjKey struct {
+	Name     string
+	Instance string
+}
+
+// RGWBucketEntryVer is a rgw_bucket_entry_ver.
+type RGWBucketEntryVer struct {
+	Pool  int64
+	Epoch uint64
+}
+
+// RGWBucketDirEntryMeta is a rgw_bucket_dir_entry_meta.
+type RGWBucketDirEntryMeta struct {
+	Category         RGWObjCategory
+	Size             uint64
+	Mtime            time.Time
+	ETag             string
+	Owner            string
+	OwnerDisplayName string
+	ContentType      string
+	AccountedSize    uint64
+	UserData         string
+	StorageClass     string
+	Appendable       bool
+}
+
+// RGWBucketPendingInfo is a rgw_bucket_pending_info.
+type RGWBucketPendingInfo struct {
+	State     RGWPendingState
+	Timestamp time.Time
+	Op        RGWModifyOp
+}
+
+// RGWBucketPending is an entry of the pending_map multimap of a
+// rgw_bucket_dir_entry, keyed by the tag of the operation.
+type RGWBucketPending struct {
+	Tag  string
+	Info RGWBucketPendingInfo
+}
+
+// RGWBucketDirEntry is a rgw_bucket_dir_entry, the omap value of a plain
+// bucket index entry of a .dir.<marker>.<shard> object.
+type RGWBucketDirEntry struct {
+	Key            ClsRGWObjKey
+	Ver            RGWBucketEntryVer
+	Locator        string
+	Exists         bool
+	Meta           RGWBucketDirEntryMeta
+	PendingMap     []RGWBucketPending
+	IndexVer       uint64
+	Tag            string
+	Flags          uint16
+	VersionedEpoch uint64
+}
+
+func DecodeRGWBucketDirEntry(data []byte, opts ...DecodeOptions) (*RGWBucketDirEntry, error) {
+	d := NewDecoder(data, opts...)
+	e, err := d.DecodeRGWBucketDirEntry()
+	if err != nil {
+		return nil, err
+	}
+	return e, d.Done()
+}
+
+// DecodePackedVal decodes an integer written by encode_packed_val of cls_rgw:
+// values below 0x80 take one byte, larger ones a marker byte with the size
+// followed by the value.
+func (d *Decoder) DecodePackedVal() (uint64, error) {
+	c, err := d.DecodeU8()
+	if err != nil {
+		return 0, err
+	}
+	if c < 0x80 {
+		return uint64(c), nil
+	}
+	switch c &^ 0x80 {
+	case 1:
+		v, err := d.DecodeU8()
+		return uint64(v), err
+	case 2:
+		v, err := d.DecodeU16()
+		return uint64(v), err
+	case 4:
+		v, err := d.DecodeU32()
+		return uint64(v), err
+	case 8:
+		return d.DecodeU64()
+	}
+	return 0, d.NewError(ErrIncompatible)
+}
+
+// DecodeRGWBucketEntryVer decodes a rgw_bucket_entry_ver.
+func (d *Decoder) DecodeRGWBucketEntryVer() (*RGWBucketEntryVer, error) {
+	var r RGWBucketEntryVer
+	_, _, structEnd, err := d.DecodeStart("rgw_bucket_entry_ver", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("pool")
+	if r.Pool, err = d.DecodeI64(); err != nil {
+		return nil, err
+	}
+	d.Field("epoch")
+	if r.Epoch, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketDirEntryMeta decodes a rgw_bucket_dir_entry_meta.
+func (d *Decoder) DecodeRGWBucketDirEntryMeta() (*RGWBucketDirEntryMeta, error) {
+	var r RGWBucketDirEntryMeta
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket_dir_entry_meta", dirEntryMetaMaxSupportedV, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("category")
+	category, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Category = RGWObjCategory(category)
+	d.Field("size")
+	if r.Size, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("mtime")
+	if r.Mtime, err = d.DecodeRealTime(); err != nil {
+		return nil, err
+	}
+	d.Field("etag")
+	if r.ETag, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("owner")
+	if r.Owner, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("owner_display_name")
+	if r.OwnerDisplayName, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("content_type")
+		if r.ContentType, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		d.Field("accounted_size")
+		if r.AccountedSize, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.AccountedSize = r.Size
+	}
+	if structV >= 5 {
+		d.Field("user_data")
+		if r.UserData, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 6 {
+		d.Field("storage_class")
+		if r.StorageClass, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 7 {
+		d.Field("appendable")
+		if r.Appendable, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketPendingInfo decodes a rgw_bucket_pending_info.
+func (d *Decoder) DecodeRGWBucketPendingInfo() (*RGWBucketPendingInfo, error) {
+	var r RGWBucketPendingInfo
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket_pending_info", 2, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("state")
+	state, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.State = RGWPendingState(state)
+	d.Field("timestamp")
+	if r.Timestamp, err = d.DecodeRealTime(); err != nil {
+		return nil, err
+	}
+	d.Field("op")
+	op, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Op = RGWModifyOp(op)
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketDirEntry decodes a rgw_bucket_dir_entry. Entries older than
+// v4 have no pool in their version, it is set to -1 like Ceph does.
+func (d *Decoder) DecodeRGWBucketDirEntry() (*RGWBucketDirEntry, error) {
+	var r RGWBucketDirEntry
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket_dir_entry", dirEntryMaxSupportedV, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("key.name")
+	if r.Key.Name, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("ver.epoch")
+	if r.Ver.Epoch, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("exists")
+	if r.Exists, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	d.Field("meta")
+	meta, err := d.DecodeRGWBucketDirEntryMeta()
+	if err != nil {
+		return nil, err
+	}
+	r.Meta = *meta
+	err = d.DecodeMap("pending_map", func(i uint32) error {
+		tag, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		info, err := d.DecodeRGWBucketPendingInfo()
+		if err != nil {
+			return err
+		}
+		r.PendingMap = append(r.PendingMap, RGWBucketPending{Tag: tag, Info: *info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("locator")
+		if r.Locator, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		d.Field("ver")
+		ver, err := d.DecodeRGWBucketEntryVer()
+		if err != nil {
+			return nil, err
+		}
+		r.Ver = *ver
+	} else {
+		r.Ver.Pool = -1
+	}
+	if structV >= 5 {
+		d.Field("index_ver")
+		if r.IndexVer, err = d.DecodePackedVal(); err != nil {
+			return nil, err
+		}
+		d.Field("tag")
+		if r.Tag, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 6 {
+		d.Field("key.instance")
+		if r.Key.Instance, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 7 {
+		d.Field("flags")
+		if r.Flags, err = d.DecodeU16(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 8 {
+		d.Field("versioned_epoch")
+		if r.VersionedEpoch, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// EncodePackedVal encodes v like encode_packed_val of cls_rgw, including its
+// inclusive upper bounds: 0x10000 gets the u16 form, truncated to 0, and
+// 0x1000000 the u32 form, while values in between that fit in a u32 still
+// take the u64 form.
+func (e *Encoder) EncodePackedVal(v uint64) {
+	switch {
+	case v < 0x80:
+		e.EncodeU8(uint8(v))
+	case v < 0x100:
+		e.EncodeU8(0x81)
+		e.EncodeU8(uint8(v))
+	case v <= 0x10000:
+		e.EncodeU8(0x82)
+		e.EncodeU16(uint16(v))
+	case v <= 0x1000000:
+		e.EncodeU8(0x84)
+		e.EncodeU32(uint32(v))
+	default:
+		e.EncodeU8(0x88)
+		e.EncodeU64(v)
+	}
+}
+
+func (e *Encoder) EncodeRGWBucketEntryVer(r *RGWBucketEntryVer) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeI64(r.Pool)
+	e.EncodeU64(r.Epoch)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBucketDirEntryMeta(r *RGWBucketDirEntryMeta) {
+	start := e.EncodeStart(dirEntryMetaMaxSupportedV, 3)
+	e.EncodeU8(uint8(r.Category))
+	e.EncodeU64(r.Size)
+	e.EncodeRealTime(r.Mtime)
+	e.EncodeString(r.ETag)
+	e.EncodeString(r.Owner)
+	e.EncodeString(r.OwnerDisplayName)
+	e.EncodeString(r.ContentType)
+	e.EncodeU64(r.AccountedSize)
+	e.EncodeString(r.UserData)
+	e.EncodeString(r.StorageClass)
+	e.EncodeBool(r.Appendable)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBucketPendingInfo(r *RGWBucketPendingInfo) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeU8(uint8(r.State))
+	e.EncodeRealTime(r.Timestamp)
+	e.EncodeU8(uint8(r.Op))
+	e.EncodeFinish(start)
+}
+
+// EncodeRGWBucketDirEntry encodes r in the v8 format. The pending map is
+// written sorted by tag, like std::multimap keeps it.
+func (e *Encoder) EncodeRGWBucketDirEntry(r *RGWBucketDirEntry) {
+	start := e.EncodeStart(dirEntryMaxSupportedV, 3)
+	e.EncodeString(r.Key.Name)
+	e.EncodeU64(r.Ver.Epoch)
+	e.EncodeBool(r.Exists)
+	e.EncodeRGWBucketDirEntryMeta(&r.Meta)
+	pending := append([]RGWBucketPending(nil), r.PendingMap...)
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Tag < pending[j].Tag })
+	e.EncodeList(len(pending), func(i int) {
+		e.EncodeString(pending[i].Tag)
+		e.EncodeRGWBucketPendingInfo(&pending[i].Info)
+	})
+	e.EncodeString(r.Locator)
+	e.EncodeRGWBucketEntryVer(&r.Ver)
+	e.EncodePackedVal(r.IndexVer)
+	e.EncodeString(r.Tag)
+	e.EncodeString(r.Key.Instance)
+	e.EncodeU16(r.Flags)
+	e.EncodeU64(r.VersionedEpoch)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWBucketDirEntry(r *RGWBucketDirEntry) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucketDirEntry(r)
+	return e.Bytes()
+}
+
+// RGWBucketCategoryStats is a rgw_bucket_category_stats.
+type RGWBucketCategoryStats struct {
+	TotalSize        uint64
+	TotalSizeRounded uint64
+	NumEntries       uint64
+	ActualSize       uint64
+}
+
+// ClsRGWBucketInstanceEntry is a cls_rgw_bucket_instance_entry, the reshard
+// state of a bucket index shard.
+type ClsRGWBucketInstanceEntry struct {
+	ReshardStatus       ReshardStatus
+	NewBucketInstanceID string
+	NumShards           int32
+}
+
+// RGWBucketDirHeader is a rgw_bucket_dir_header, the omap header of a
+// .dir.<marker>.<shard> object.
+type RGWBucketDirHeader struct {
+	Stats       map[RGWObjCategory]RGWBucketCategoryStats
+	TagTimeout  uint64
+	Ver         uint64
+	MasterVer   uint64
+	MaxMarker   string
+	NewInstance ClsRGWBucketInstanceEntry
+	SyncStopped bool
+}
+
+// MainStats returns the sum of the rgw.main stats of the shard headers of a
+// bucket, which is what radosgw accounts in the size, rounded size and count
+// of the cls_user_bucket_entry of the bucket.
+func MainStats(headers ...*RGWBucketDirHeader) RGWBucketCategoryStats {
+	var sum RGWBucketCategoryStats
+	for _, h := range headers {
+		s := h.Stats[RGWObjCategoryMain]
+		sum.TotalSize += s.TotalSize
+		sum.TotalSizeRounded += s.TotalSizeRounded
+		sum.NumEntries += s.NumEntries
+		sum.ActualSize += s.ActualSize
+	}
+	return sum
+}
+
+// CheckStats returns an error wrapping ErrStatsMismatch if the stats of the
+// user's bucket entry differ from the MainStats of the bucket's index shard
+// headers.
+func (u *UserBucketEntry) CheckStats(headers ...*RGWBucketDirHeader) error {
+	s := MainStats(headers...)
+	switch {
+	case u.Size != s.TotalSize:
+		return fmt.Errorf("%w: size %d, index has %d", ErrStatsMismatch, u.Size, s.TotalSize)
+	case u.SizeRounded != s.TotalSizeRounded:
+		return fmt.Errorf("%w: size_rounded %d, index has %d", ErrStatsMismatch, u.SizeRounded, s.TotalSizeRounded)
+	case u.Count != s.NumEntries:
+		return fmt.Errorf("%w: count %d, index has %d", ErrStatsMismatch, u.Count, s.NumEntries)
+	}
+	return nil
+}
+
+func DecodeRGWBucketDirHeader(data []byte, opts ...DecodeOptions) (*RGWBucketDirHeader, error) {
+	d := NewDecoder(data, opts...)
+	h, err := d.DecodeRGWBucketDirHeader()
+	if err != nil {
+		return nil, err
+	}
+	return h, d.Done()
+}
+
+// DecodeRGWBucketCategoryStats decodes a rgw_bucket_category_stats.
+func (d *Decoder) DecodeRGWBucketCategoryStats() (*RGWBucketCategoryStats, error) {
+	var r RGWBucketCategoryStats
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket_category_stats", 3, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("total_size")
+	if r.TotalSize, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("total_size_rounded")
+	if r.TotalSizeRounded, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("num_entries")
+	if r.NumEntries, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	if structV >= 3 {
+		d.Field("actual_size")
+		if r.ActualSize, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.ActualSize = r.TotalSize
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeClsRGWBucketInstanceEntry decodes a cls_rgw_bucket_instance_entry.
+func (d *Decoder) DecodeClsRGWBucketInstanceEntry() (*ClsRGWBucketInstanceEntry, error) {
+	var r ClsRGWBucketInstanceEntry
+	_, _, structEnd, err := d.DecodeStart("cls_rgw_bucket_instance_entry", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("reshard_status")
+	status, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.ReshardStatus = ReshardStatus(status)
+	d.Field("new_bucket_instance_id")
+	if r.NewBucketInstanceID, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("num_shards")
+	if r.NumShards, err = d.DecodeI32(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketDirHeader decodes a rgw_bucket_dir_header.
+func (d *Decoder) DecodeRGWBucketDirHeader() (*RGWBucketDirHeader, error) {
+	r := RGWBucketDirHeader{
+		Stats:       make(map[RGWObjCategory]RGWBucketCategoryStats),
+		NewInstance: ClsRGWBucketInstanceEntry{NumShards: -1},
+	}
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket_dir_header", dirHeaderMaxSupportedV, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	err = d.DecodeMap("stats", func(i uint32) error {
+		category, err := d.DecodeU8()
+		if err != nil {
+			return err
+		}
+		s, err := d.DecodeRGWBucketCategoryStats()
+		if err != nil {
+			return err
+		}
+		r.Stats[RGWObjCategory(category)] = *s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if structV >= 3 {
+		d.Field("tag_timeout")
+		if r.TagTimeout, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		d.Field("ver")
+		if r.Ver, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+		d.Field("master_ver")
+		if r.MasterVer, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 5 {
+		d.Field("max_marker")
+		if r.MaxMarker, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 6 {
+		d.Field("new_instance")
+		instance, err := d.DecodeClsRGWBucketInstanceEntry()
+		if err != nil {
+			return nil, err
+		}
+		r.NewInstance = *instance
+	}
+	if structV >= 7 {
+		d.Field("syncstopped")
+		if r.SyncStopped, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeRGWBucketCategoryStats(r *RGWBucketCategoryStats) {
+	start := e.EncodeStart(3, 2)
+	e.EncodeU64(r.TotalSize)
+	e.EncodeU64(r.TotalSizeRounded)
+	e.EncodeU64(r.NumEntries)
+	e.EncodeU64(r.ActualSize)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeClsRGWBucketInstanceEntry(r *ClsRGWBucketInstanceEntry) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU8(uint8(r.ReshardStatus))
+	e.EncodeString(r.NewBucketInstanceID)
+	e.EncodeI32(r.NumShards)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBucketDirHeader(r *RGWBucketDirHeader) {
+	start := e.EncodeStart(dirHeaderMaxSupportedV, 2)
+	categories := r.sortedCategories()
+	e.EncodeList(len(categories), func(i int) {
+		s := r.Stats[categories[i]]
+		e.EncodeU8(uint8(categories[i]))
+		e.EncodeRGWBucketCategoryStats(&s)
+	})
+	e.EncodeU64(r.TagTimeout)
+	e.EncodeU64(r.Ver)
+	e.EncodeU64(r.MasterVer)
+	e.EncodeString(r.MaxMarker)
+	e.EncodeClsRGWBucketInstanceEntry(&r.NewInstance)
+	e.EncodeBool(r.SyncStopped)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWBucketDirHeader(r *RGWBucketDirHeader) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucketDirHeader(r)
+	return e.Bytes()
+}
+
+func (r *RGWBucketDirHeader) sortedCategories() []RGWObjCategory {
+	categories := make([]RGWObjCategory, 0, len(r.Stats))
+	for c := range r.Stats {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+	return categories
+}
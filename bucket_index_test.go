@@ -0,0 +1,223 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeRGWBucketDirEntry(t *testing.T) {
+	expected := &RGWBucketDirEntry{
+		Key:    ClsRGWObjKey{Name: "photos/cat.jpg", Instance: "XgfYaAdQ1ZnUVBLQ47Jyk8jrGO1ISM4"},
+		Ver:    RGWBucketEntryVer{Pool: 7, Epoch: 42},
+		Exists: true,
+		Meta: RGWBucketDirEntryMeta{
+			Category:         RGWObjCategoryMain,
+			Size:             1 << 20,
+			Mtime:            time.Unix(1600000000, 500).UTC(),
+			ETag:             "d41d8cd98f00b204e9800998ecf8427e",
+			Owner:            "user",
+			OwnerDisplayName: "User",
+			ContentType:      "image/jpeg",
+			AccountedSize:    1 << 19,
+			StorageClass:     "COLD",
+			Appendable:       true,
+		},
+		PendingMap: []RGWBucketPending{
+			{Tag: "a", Info: RGWBucketPendingInfo{State: PendingStateModify, Timestamp: time.Unix(1600000001, 0).UTC(), Op: OpDel}},
+			{Tag: "a", Info: RGWBucketPendingInfo{State: PendingStateComplete, Op: OpAdd}},
+			{Tag: "b", Info: RGWBucketPendingInfo{Op: OpLinkOLH}},
+		},
+		IndexVer:       300,
+		Tag:            "zone.4217.123",
+		Flags:          DirEntryFlagVer | DirEntryFlagCurrent,
+		VersionedEpoch: 3,
+	}
+	data := EncodeRGWBucketDirEntry(expected)
+	actual, err := DecodeRGWBucketDirEntry(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWBucketDirEntry(actual))
+}
+
+func TestDecodeRGWBucketDirEntryFirefly(t *testing.T) {
+	// A rgw_bucket_dir_entry v3 with a rgw_bucket_dir_entry_meta v3, as
+	// written by Firefly.
+	e := NewEncoder()
+	start := e.EncodeStart(3, 3)
+	e.EncodeString("obj")
+	e.EncodeU64(5)
+	e.EncodeBool(true)
+	meta := e.EncodeStart(3, 3)
+	e.EncodeU8(uint8(RGWObjCategoryMain))
+	e.EncodeU64(1234)
+	e.EncodeTime(1400000000, 0)
+	e.EncodeString("etag")
+	e.EncodeString("user")
+	e.EncodeString("User")
+	e.EncodeString("text/plain")
+	e.EncodeFinish(meta)
+	e.EncodeU32(0)
+	e.EncodeString("locator")
+	e.EncodeFinish(start)
+
+	entry, err := DecodeRGWBucketDirEntry(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, &RGWBucketDirEntry{
+		Key:     ClsRGWObjKey{Name: "obj"},
+		Ver:     RGWBucketEntryVer{Pool: -1, Epoch: 5},
+		Locator: "locator",
+		Exists:  true,
+		Meta: RGWBucketDirEntryMeta{
+			Category:         RGWObjCategoryMain,
+			Size:             1234,
+			Mtime:            time.Unix(1400000000, 0).UTC(),
+			ETag:             "etag",
+			Owner:            "user",
+			OwnerDisplayName: "User",
+			ContentType:      "text/plain",
+			AccountedSize:    1234,
+		},
+	}, entry)
+
+	out, err := json.Marshal(entry)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "obj",
+		"instance": "",
+		"ver": {"pool": -1, "epoch": 5},
+		"locator": "locator",
+		"exists": true,
+		"meta": {
+			"category": 1,
+			"size": 1234,
+			"mtime": "2014-05-13T16:53:20.000000Z",
+			"etag": "etag",
+			"storage_class": "",
+			"owner": "user",
+			"owner_display_name": "User",
+			"content_type": "text/plain",
+			"accounted_size": 1234,
+			"user_data": "",
+			"appendable": false
+		},
+		"tag": "",
+		"flags": 0,
+		"pending_map": [],
+		"versioned_epoch": 0
+	}`, string(out))
+}
+
+func TestPackedVal(t *testing.T) {
+	testcases := []struct {
+		v       uint64
+		encoded []byte
+	}{
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x81, 0x80}},
+		{0x1234, []byte{0x82, 0x34, 0x12}},
+		{0x10001, []byte{0x84, 0x01, 0x00, 0x01, 0x00}},
+		{0x1000000, []byte{0x84, 0x00, 0x00, 0x00, 0x01}},
+		{0x2000000, []byte{0x88, 0x00, 0x00, 0x00, 0x02, 0, 0, 0, 0}},
+		{0x12345678, []byte{0x88, 0x78, 0x56, 0x34, 0x12, 0, 0, 0, 0}},
+		{1 << 40, []byte{0x88, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, tt := range testcases {
+		e := NewEncoder()
+		e.EncodePackedVal(tt.v)
+		assert.Equal(t, tt.encoded, e.Bytes())
+		d := NewDecoder(tt.encoded)
+		v, err := d.DecodePackedVal()
+		assert.NoError(t, err)
+		assert.Equal(t, tt.v, v)
+	}
+
+	// Like Ceph, 0x10000 is written in the u16 form and reads back as 0.
+	e := NewEncoder()
+	e.EncodePackedVal(0x10000)
+	assert.Equal(t, []byte{0x82, 0x00, 0x00}, e.Bytes())
+
+	d := NewDecoder([]byte{0x83, 0})
+	_, err := d.DecodePackedVal()
+	assert.True(t, errors.Is(err, ErrIncompatible))
+}
+
+func TestEncodeDecodeRGWBucketDirHeader(t *testing.T) {
+	expected := &RGWBucketDirHeader{
+		Stats: map[RGWObjCategory]RGWBucketCategoryStats{
+			RGWObjCategoryMain:      {TotalSize: 10000, TotalSizeRounded: 12288, NumEntries: 3, ActualSize: 10000},
+			RGWObjCategoryMultiMeta: {NumEntries: 1},
+		},
+		TagTimeout: 120,
+		Ver:        17,
+		MasterVer:  2,
+		MaxMarker:  "00000000017.17.5",
+		NewInstance: ClsRGWBucketInstanceEntry{
+			ReshardStatus:       ReshardInProgress,
+			NewBucketInstanceID: "zone.4217.9",
+			NumShards:           23,
+		},
+		SyncStopped: true,
+	}
+	data := EncodeRGWBucketDirHeader(expected)
+	actual, err := DecodeRGWBucketDirHeader(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWBucketDirHeader(actual))
+
+	out, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"ver": 17,
+		"master_ver": 2,
+		"stats": [
+			1, {"total_size": 10000, "total_size_rounded": 12288, "num_entries": 3, "actual_size": 10000},
+			3, {"total_size": 0, "total_size_rounded": 0, "num_entries": 1, "actual_size": 0}
+		],
+		"new_instance": {"reshard_status": "in-progress", "new_bucket_instance_id": "zone.4217.9", "num_shards": 23}
+	}`, string(out))
+}
+
+func TestDecodeRGWBucketDirHeaderV2(t *testing.T) {
+	e := NewEncoder()
+	start := e.EncodeStart(2, 2)
+	e.EncodeU32(1)
+	e.EncodeU8(uint8(RGWObjCategoryMain))
+	stats := e.EncodeStart(2, 2)
+	e.EncodeU64(100)
+	e.EncodeU64(4096)
+	e.EncodeU64(1)
+	e.EncodeFinish(stats)
+	e.EncodeFinish(start)
+
+	h, err := DecodeRGWBucketDirHeader(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, &RGWBucketDirHeader{
+		Stats: map[RGWObjCategory]RGWBucketCategoryStats{
+			RGWObjCategoryMain: {TotalSize: 100, TotalSizeRounded: 4096, NumEntries: 1, ActualSize: 100},
+		},
+		NewInstance: ClsRGWBucketInstanceEntry{NumShards: -1},
+	}, h)
+}
+
+func TestUserBucketEntryCheckStats(t *testing.T) {
+	shard := func(size, rounded, n uint64) *RGWBucketDirHeader {
+		return &RGWBucketDirHeader{Stats: map[RGWObjCategory]RGWBucketCategoryStats{
+			RGWObjCategoryMain:      {TotalSize: size, TotalSizeRounded: rounded, NumEntries: n},
+			RGWObjCategoryMultiMeta: {TotalSize: 1, TotalSizeRounded: 4096, NumEntries: 1},
+		}}
+	}
+	headers := []*RGWBucketDirHeader{shard(100, 4096, 1), shard(5000, 8192, 2), {}}
+	assert.Equal(t, RGWBucketCategoryStats{TotalSize: 5100, TotalSizeRounded: 12288, NumEntries: 3}, MainStats(headers...))
+
+	u := &UserBucketEntry{Size: 5100, SizeRounded: 12288, Count: 3}
+	assert.NoError(t, u.CheckStats(headers...))
+	u.Count = 4
+	err := u.CheckStats(headers...)
+	assert.True(t, errors.Is(err, ErrStatsMismatch))
+	assert.EqualError(t, err, "bucket stats do not match the bucket index: count 4, index has 3")
+}
@@ -0,0 +1,780 @@
+package decoder
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bucket flags of RGWBucketInfo.
+const (
+	BucketSuspended         = 0x1
+	BucketVersioned         = 0x2
+	BucketVersionsSuspended = 0x4
+	BucketDatasyncDisabled  = 0x8
+	BucketMFAEnabled        = 0x10
+	BucketObjectLockEnabled = 0x20
+)
+
+const (
+	bucketInfoRealTimeV       = 17
+	bucketInfoLayoutV         = 22
+	bucketInfoOwnerNSV        = 23
+	bucketInfoMaxSupportedV   = 23
+	bucketLayoutLogsV         = 2
+	bucketLayoutMaxSupportedV = 2
+)
+
+// RGWUser is a rgw_user, which prints as "tenant$ns$id".
+type RGWUser struct {
+	Tenant string
+	ID     string
+	NS     string
+}
+
+// String returns the user as rgw_user::to_str does.
+func (u RGWUser) String() string {
+	switch {
+	case u.Tenant != "" && u.NS != "":
+		return u.Tenant + "$" + u.NS + "$" + u.ID
+	case u.Tenant != "":
+		return u.Tenant + "$" + u.ID
+	case u.NS != "":
+		return "$" + u.NS + "$" + u.ID
+	}
+	return u.ID
+}
+
+// parseRGWUser parses s like rgw_user::from_str.
+func parseRGWUser(s string) RGWUser {
+	pos := strings.IndexByte(s, '$')
+	if pos < 0 {
+		return RGWUser{ID: s}
+	}
+	u := RGWUser{Tenant: s[:pos]}
+	rest := s[pos+1:]
+	if pos := strings.IndexByte(rest, '$'); pos >= 0 {
+		u.NS = rest[:pos]
+		u.ID = rest[pos+1:]
+	} else {
+		u.ID = rest
+	}
+	return u
+}
+
+// RGWQuotaInfo is a RGWQuotaInfo. MaxSize and MaxObjects are negative when
+// unlimited.
+type RGWQuotaInfo struct {
+	MaxSize    int64
+	MaxObjects int64
+	Enabled    bool
+	CheckOnRaw bool
+}
+
+// BucketIndexType is a rgw::BucketIndexType.
+type BucketIndexType uint8
+
+const (
+	BucketIndexNormal BucketIndexType = iota
+	BucketIndexIndexless
+)
+
+func (t BucketIndexType) String() string {
+	switch t {
+	case BucketIndexNormal:
+		return "Normal"
+	case BucketIndexIndexless:
+		return "Indexless"
+	}
+	return "Unknown"
+}
+
+// BucketHashType is a rgw::BucketHashType.
+type BucketHashType uint8
+
+const BucketHashMod BucketHashType = 0
+
+func (t BucketHashType) String() string {
+	if t == BucketHashMod {
+		return "Mod"
+	}
+	return "Unknown"
+}
+
+// BucketReshardState is a rgw::BucketReshardState.
+type BucketReshardState uint8
+
+const (
+	BucketReshardNone BucketReshardState = iota
+	BucketReshardInProgress
+)
+
+func (s BucketReshardState) String() string {
+	switch s {
+	case BucketReshardNone:
+		return "None"
+	case BucketReshardInProgress:
+		return "InProgress"
+	}
+	return "Unknown"
+}
+
+// BucketLogType is a rgw::BucketLogType.
+type BucketLogType uint8
+
+const (
+	BucketLogInIndex BucketLogType = iota
+	BucketLogDeleted
+)
+
+func (t BucketLogType) String() string {
+	switch t {
+	case BucketLogInIndex:
+		return "InIndex"
+	case BucketLogDeleted:
+		return "Deleted"
+	}
+	return "Unknown"
+}
+
+// ReshardStatus is a cls_rgw_reshard_status.
+type ReshardStatus uint8
+
+const (
+	ReshardNotResharding ReshardStatus = iota
+	ReshardInProgress
+	ReshardDone
+)
+
+// String returns the status as cls_rgw_reshard_status_to_str does.
+func (s ReshardStatus) String() string {
+	switch s {
+	case ReshardNotResharding:
+		return "not-resharding"
+	case ReshardInProgress:
+		return "in-progress"
+	case ReshardDone:
+		return "done"
+	}
+	return "Unknown status"
+}
+
+// BucketIndexNormalLayout is a rgw::bucket_index_normal_layout.
+type BucketIndexNormalLayout struct {
+	NumShards uint32
+	HashType  BucketHashType
+}
+
+// BucketIndexLayout is a rgw::bucket_index_layout.
+type BucketIndexLayout struct {
+	Type   BucketIndexType
+	Normal BucketIndexNormalLayout
+}
+
+// BucketIndexLayoutGeneration is a rgw::bucket_index_layout_generation.
+type BucketIndexLayoutGeneration struct {
+	Gen    uint64
+	Layout BucketIndexLayout
+}
+
+// BucketIndexLogLayout is a rgw::bucket_index_log_layout.
+type BucketIndexLogLayout struct {
+	Gen    uint64
+	Layout BucketIndexNormalLayout
+}
+
+// BucketLogLayout is a rgw::bucket_log_layout.
+type BucketLogLayout struct {
+	Type    BucketLogType
+	InIndex BucketIndexLogLayout
+}
+
+// BucketLogLayoutGeneration is a rgw::bucket_log_layout_generation.
+type BucketLogLayoutGeneration struct {
+	Gen    uint64
+	Layout BucketLogLayout
+}
+
+// BucketLayout is a rgw::BucketLayout. TargetIndex is only set while the
+// bucket is resharded.
+type BucketLayout struct {
+	Resharding   BucketReshardState
+	CurrentIndex BucketIndexLayoutGeneration
+	TargetIndex  *BucketIndexLayoutGeneration
+	Logs         []BucketLogLayoutGeneration
+}
+
+// RGWBucketInfo is a RGWBucketInfo, the content of the bucket instance
+// object .bucket.meta.<bucket>:<instance>. The website configuration, object
+// lock configuration and sync policy are kept in their encoded form.
+//
+// Every encoding carries the shard count, hash type and index type of the
+// current index as separate fields. They are decoded into Layout.CurrentIndex,
+// which v22 and later overwrite with the encoded layout, like Ceph does.
+type RGWBucketInfo struct {
+	Bucket              RGWBucket
+	Owner               RGWUser
+	Flags               uint32
+	Zonegroup           string
+	CreationTime        time.Time
+	PlacementRule       RGWPlacementRule
+	HasInstanceObj      bool
+	Quota               RGWQuotaInfo
+	RequesterPays       bool
+	HasWebsite          bool
+	WebsiteConf         []byte
+	SwiftVersioning     bool
+	SwiftVerLocation    string
+	MDSearchConfig      map[string]uint32
+	ReshardStatus       ReshardStatus
+	NewBucketInstanceID string
+	ObjectLock          []byte
+	SyncPolicy          []byte
+	Layout              BucketLayout
+}
+
+// Versioning returns the S3 versioning status of the bucket: "Enabled",
+// "Suspended" or "" if versioning was never enabled.
+func (b *RGWBucketInfo) Versioning() string {
+	switch {
+	case b.Flags&BucketVersionsSuspended != 0:
+		return "Suspended"
+	case b.Flags&BucketVersioned != 0:
+		return "Enabled"
+	}
+	return ""
+}
+
+// MFADelete reports whether MFA delete is enabled.
+func (b *RGWBucketInfo) MFADelete() bool {
+	return b.Flags&BucketMFAEnabled != 0
+}
+
+func DecodeRGWBucketInfo(data []byte, opts ...DecodeOptions) (*RGWBucketInfo, error) {
+	d := NewDecoder(data, opts...)
+	b, err := d.DecodeRGWBucketInfo()
+	if err != nil {
+		return nil, err
+	}
+	return b, d.Done()
+}
+
+// DecodeRGWQuotaInfo decodes a RGWQuotaInfo.
+func (d *Decoder) DecodeRGWQuotaInfo() (*RGWQuotaInfo, error) {
+	var r RGWQuotaInfo
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("RGWQuotaInfo", 3, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("max_size_kb")
+	maxSizeKB, err := d.DecodeI64()
+	if err != nil {
+		return nil, err
+	}
+	d.Field("max_objects")
+	if r.MaxObjects, err = d.DecodeI64(); err != nil {
+		return nil, err
+	}
+	d.Field("enabled")
+	if r.Enabled, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("max_size")
+		if r.MaxSize, err = d.DecodeI64(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.MaxSize = maxSizeKB * 1024
+	}
+	if structV >= 3 {
+		d.Field("check_on_raw")
+		if r.CheckOnRaw, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeBucketIndexNormalLayout decodes a rgw::bucket_index_normal_layout.
+func (d *Decoder) DecodeBucketIndexNormalLayout() (*BucketIndexNormalLayout, error) {
+	var r BucketIndexNormalLayout
+	_, _, structEnd, err := d.DecodeStart("bucket_index_normal_layout", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("num_shards")
+	if r.NumShards, err = d.DecodeU32(); err != nil {
+		return nil, err
+	}
+	d.Field("hash_type")
+	hashType, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.HashType = BucketHashType(hashType)
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeBucketIndexLayoutGeneration decodes a
+// rgw::bucket_index_layout_generation.
+func (d *Decoder) DecodeBucketIndexLayoutGeneration() (*BucketIndexLayoutGeneration, error) {
+	var r BucketIndexLayoutGeneration
+	_, _, structEnd, err := d.DecodeStart("bucket_index_layout_generation", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("gen")
+	if r.Gen, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("layout")
+	_, _, layoutEnd, err := d.DecodeStart("bucket_index_layout", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("type")
+	typ, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Layout.Type = BucketIndexType(typ)
+	if r.Layout.Type == BucketIndexNormal {
+		d.Field("normal")
+		normal, err := d.DecodeBucketIndexNormalLayout()
+		if err != nil {
+			return nil, err
+		}
+		r.Layout.Normal = *normal
+	}
+	if err := d.DecodeFinish(layoutEnd); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeBucketLogLayoutGeneration decodes a
+// rgw::bucket_log_layout_generation.
+func (d *Decoder) DecodeBucketLogLayoutGeneration() (*BucketLogLayoutGeneration, error) {
+	var r BucketLogLayoutGeneration
+	_, _, structEnd, err := d.DecodeStart("bucket_log_layout_generation", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("gen")
+	if r.Gen, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("layout")
+	_, _, layoutEnd, err := d.DecodeStart("bucket_log_layout", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("type")
+	typ, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Layout.Type = BucketLogType(typ)
+	if r.Layout.Type == BucketLogInIndex {
+		d.Field("in_index")
+		_, _, inIndexEnd, err := d.DecodeStart("bucket_index_log_layout", 1)
+		if err != nil {
+			return nil, err
+		}
+		d.Field("gen")
+		if r.Layout.InIndex.Gen, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+		d.Field("layout")
+		normal, err := d.DecodeBucketIndexNormalLayout()
+		if err != nil {
+			return nil, err
+		}
+		r.Layout.InIndex.Layout = *normal
+		if err := d.DecodeFinish(inIndexEnd); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.DecodeFinish(layoutEnd); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeBucketLayout decodes a rgw::BucketLayout.
+func (d *Decoder) DecodeBucketLayout() (*BucketLayout, error) {
+	var r BucketLayout
+	structV, _, structEnd, err := d.DecodeStart("BucketLayout", bucketLayoutMaxSupportedV)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("resharding")
+	resharding, err := d.DecodeU8()
+	if err != nil {
+		return nil, err
+	}
+	r.Resharding = BucketReshardState(resharding)
+	d.Field("current_index")
+	current, err := d.DecodeBucketIndexLayoutGeneration()
+	if err != nil {
+		return nil, err
+	}
+	r.CurrentIndex = *current
+	d.Field("target_index")
+	_, err = d.DecodeOptional(func() error {
+		target, err := d.DecodeBucketIndexLayoutGeneration()
+		r.TargetIndex = target
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if structV < bucketLayoutLogsV {
+		// Like Ceph, give older layouts a log matching the current index.
+		if r.CurrentIndex.Layout.Type == BucketIndexNormal {
+			r.Logs = []BucketLogLayoutGeneration{{
+				Layout: BucketLogLayout{
+					Type: BucketLogInIndex,
+					InIndex: BucketIndexLogLayout{
+						Gen:    r.CurrentIndex.Gen,
+						Layout: r.CurrentIndex.Layout.Normal,
+					},
+				},
+			}}
+		}
+	} else {
+		err = d.DecodeList("logs", func(i uint32) error {
+			l, err := d.DecodeBucketLogLayoutGeneration()
+			if err != nil {
+				return err
+			}
+			r.Logs = append(r.Logs, *l)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWBucketInfo decodes a RGWBucketInfo.
+func (d *Decoder) DecodeRGWBucketInfo() (*RGWBucketInfo, error) {
+	var r RGWBucketInfo
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen32("RGWBucketInfo", bucketInfoMaxSupportedV, 4, 4)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("bucket")
+	bucket, err := d.DecodeRGWBucket()
+	if err != nil {
+		return nil, err
+	}
+	r.Bucket = *bucket
+	if structV >= 2 {
+		d.Field("owner")
+		owner, err := d.DecodeString()
+		if err != nil {
+			return nil, err
+		}
+		r.Owner = parseRGWUser(owner)
+	}
+	if structV >= 3 {
+		d.Field("flags")
+		if r.Flags, err = d.DecodeU32(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 5 {
+		d.Field("zonegroup")
+		if r.Zonegroup, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 6 {
+		d.Field("creation_time")
+		ct, err := d.DecodeU64()
+		if err != nil {
+			return nil, err
+		}
+		if structV < bucketInfoRealTimeV && ct != 0 {
+			r.CreationTime = time.Unix(int64(ct), 0).UTC()
+		}
+	}
+	if structV >= 7 {
+		d.Field("placement_rule")
+		rule, err := d.DecodeRGWPlacementRule()
+		if err != nil {
+			return nil, err
+		}
+		r.PlacementRule = *rule
+	}
+	if structV >= 8 {
+		d.Field("has_instance_obj")
+		if r.HasInstanceObj, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 9 {
+		d.Field("quota")
+		quota, err := d.DecodeRGWQuotaInfo()
+		if err != nil {
+			return nil, err
+		}
+		r.Quota = *quota
+	}
+	if structV >= 10 {
+		d.Field("num_shards")
+		if r.Layout.CurrentIndex.Layout.Normal.NumShards, err = d.DecodeU32(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 11 {
+		d.Field("bi_shard_hash_type")
+		hashType, err := d.DecodeU8()
+		if err != nil {
+			return nil, err
+		}
+		r.Layout.CurrentIndex.Layout.Normal.HashType = BucketHashType(hashType)
+	}
+	if structV >= 12 {
+		d.Field("requester_pays")
+		if r.RequesterPays, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 13 {
+		d.Field("owner.tenant")
+		if r.Owner.Tenant, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 14 {
+		d.Field("has_website")
+		if r.HasWebsite, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+		if r.HasWebsite {
+			d.Field("website_conf")
+			if r.WebsiteConf, err = d.DecodeRawStruct("RGWBucketWebsiteConf"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if structV >= 15 {
+		d.Field("index_type")
+		it, err := d.DecodeU32()
+		if err != nil {
+			return nil, err
+		}
+		r.Layout.CurrentIndex.Layout.Type = BucketIndexType(it)
+	}
+	if structV >= 16 {
+		d.Field("swift_versioning")
+		if r.SwiftVersioning, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+		if r.SwiftVersioning {
+			d.Field("swift_ver_location")
+			if r.SwiftVerLocation, err = d.DecodeString(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if structV >= bucketInfoRealTimeV {
+		d.Field("creation_time")
+		if r.CreationTime, err = d.DecodeRealTime(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 18 {
+		r.MDSearchConfig = make(map[string]uint32)
+		err = d.DecodeMap("mdsearch_config", func(i uint32) error {
+			k, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			v, err := d.DecodeU32()
+			if err != nil {
+				return err
+			}
+			r.MDSearchConfig[k] = v
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 19 {
+		d.Field("reshard_status")
+		status, err := d.DecodeU8()
+		if err != nil {
+			return nil, err
+		}
+		r.ReshardStatus = ReshardStatus(status)
+		d.Field("new_bucket_instance_id")
+		if r.NewBucketInstanceID, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 20 && r.Flags&BucketObjectLockEnabled != 0 {
+		d.Field("obj_lock")
+		if r.ObjectLock, err = d.DecodeRawStruct("RGWObjectLock"); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 21 {
+		d.Field("sync_policy")
+		_, err = d.DecodeOptional(func() error {
+			r.SyncPolicy, err = d.DecodeRawStruct("rgw_sync_policy_info")
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if structV >= bucketInfoLayoutV {
+		d.Field("layout")
+		layout, err := d.DecodeBucketLayout()
+		if err != nil {
+			return nil, err
+		}
+		r.Layout = *layout
+	} else if r.Layout.CurrentIndex.Layout.Type == BucketIndexNormal {
+		r.Layout.Logs = []BucketLogLayoutGeneration{{
+			Layout: BucketLogLayout{
+				Type: BucketLogInIndex,
+				InIndex: BucketIndexLogLayout{
+					Layout: r.Layout.CurrentIndex.Layout.Normal,
+				},
+			},
+		}}
+	}
+	if structV >= bucketInfoOwnerNSV {
+		d.Field("owner.ns")
+		if r.Owner.NS, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeRGWQuotaInfo(r *RGWQuotaInfo) {
+	start := e.EncodeStart(3, 1)
+	maxSizeKB := (r.MaxSize + 1023) / 1024
+	if r.MaxSize < 0 {
+		maxSizeKB = -((-r.MaxSize + 1023) / 1024)
+	}
+	e.EncodeI64(maxSizeKB)
+	e.EncodeI64(r.MaxObjects)
+	e.EncodeBool(r.Enabled)
+	e.EncodeI64(r.MaxSize)
+	e.EncodeBool(r.CheckOnRaw)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeBucketIndexNormalLayout(r *BucketIndexNormalLayout) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU32(r.NumShards)
+	e.EncodeU8(uint8(r.HashType))
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeBucketIndexLayoutGeneration(r *BucketIndexLayoutGeneration) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU64(r.Gen)
+	layout := e.EncodeStart(1, 1)
+	e.EncodeU8(uint8(r.Layout.Type))
+	if r.Layout.Type == BucketIndexNormal {
+		e.EncodeBucketIndexNormalLayout(&r.Layout.Normal)
+	}
+	e.EncodeFinish(layout)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeBucketLogLayoutGeneration(r *BucketLogLayoutGeneration) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU64(r.Gen)
+	layout := e.EncodeStart(1, 1)
+	e.EncodeU8(uint8(r.Layout.Type))
+	if r.Layout.Type == BucketLogInIndex {
+		inIndex := e.EncodeStart(1, 1)
+		e.EncodeU64(r.Layout.InIndex.Gen)
+		e.EncodeBucketIndexNormalLayout(&r.Layout.InIndex.Layout)
+		e.EncodeFinish(inIndex)
+	}
+	e.EncodeFinish(layout)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeBucketLayout(r *BucketLayout) {
+	start := e.EncodeStart(2, 1)
+	e.EncodeU8(uint8(r.Resharding))
+	e.EncodeBucketIndexLayoutGeneration(&r.CurrentIndex)
+	e.EncodeOptional(r.TargetIndex != nil, func() {
+		e.EncodeBucketIndexLayoutGeneration(r.TargetIndex)
+	})
+	e.EncodeList(len(r.Logs), func(i int) {
+		e.EncodeBucketLogLayoutGeneration(&r.Logs[i])
+	})
+	e.EncodeFinish(start)
+}
+
+// EncodeRGWBucketInfo encodes r in the v23 format. The raw website
+// configuration, object lock and sync policy are written back as they are.
+func (e *Encoder) EncodeRGWBucketInfo(r *RGWBucketInfo) {
+	start := e.EncodeStart(bucketInfoMaxSupportedV, 4)
+	e.EncodeRGWBucket(&r.Bucket)
+	e.EncodeString(r.Owner.ID)
+	e.EncodeU32(r.Flags)
+	e.EncodeString(r.Zonegroup)
+	var ct uint64
+	if !r.CreationTime.IsZero() {
+		ct = uint64(r.CreationTime.Unix())
+	}
+	e.EncodeU64(ct)
+	e.EncodeRGWPlacementRule(&r.PlacementRule)
+	e.EncodeBool(r.HasInstanceObj)
+	e.EncodeRGWQuotaInfo(&r.Quota)
+	e.EncodeU32(r.Layout.CurrentIndex.Layout.Normal.NumShards)
+	e.EncodeU8(uint8(r.Layout.CurrentIndex.Layout.Normal.HashType))
+	e.EncodeBool(r.RequesterPays)
+	e.EncodeString(r.Owner.Tenant)
+	e.EncodeBool(r.HasWebsite)
+	if r.HasWebsite {
+		e.WriteBytes(r.WebsiteConf)
+	}
+	e.EncodeU32(uint32(r.Layout.CurrentIndex.Layout.Type))
+	e.EncodeBool(r.SwiftVersioning)
+	if r.SwiftVersioning {
+		e.EncodeString(r.SwiftVerLocation)
+	}
+	e.EncodeRealTime(r.CreationTime)
+	keys := make([]string, 0, len(r.MDSearchConfig))
+	for k := range r.MDSearchConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.EncodeList(len(keys), func(i int) {
+		e.EncodeString(keys[i])
+		e.EncodeU32(r.MDSearchConfig[keys[i]])
+	})
+	e.EncodeU8(uint8(r.ReshardStatus))
+	e.EncodeString(r.NewBucketInstanceID)
+	if r.Flags&BucketObjectLockEnabled != 0 {
+		e.WriteBytes(r.ObjectLock)
+	}
+	e.EncodeOptional(r.SyncPolicy != nil, func() {
+		e.WriteBytes(r.SyncPolicy)
+	})
+	e.EncodeBucketLayout(&r.Layout)
+	e.EncodeString(r.Owner.NS)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWBucketInfo(r *RGWBucketInfo) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucketInfo(r)
+	return e.Bytes()
+}
@@ -0,0 +1,188 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testBucketInfo() *RGWBucketInfo {
+	target := BucketIndexLayoutGeneration{
+		Gen:    2,
+		Layout: BucketIndexLayout{Normal: BucketIndexNormalLayout{NumShards: 23}},
+	}
+	return &RGWBucketInfo{
+		Bucket: RGWBucket{
+			Tenant:   "tenant",
+			Name:     "bucket",
+			Marker:   "zone.1234.1",
+			BucketID: "zone.1234.2",
+		},
+		Owner:          RGWUser{Tenant: "tenant", ID: "user", NS: "ns"},
+		Flags:          BucketVersioned | BucketObjectLockEnabled,
+		Zonegroup:      "zonegroup",
+		CreationTime:   time.Unix(1600000000, 123456789).UTC(),
+		PlacementRule:  RGWPlacementRule{Name: "default-placement", StorageClass: "COLD"},
+		HasInstanceObj: true,
+		Quota: RGWQuotaInfo{
+			MaxSize:    1 << 30,
+			MaxObjects: -1,
+			Enabled:    true,
+		},
+		HasWebsite:          true,
+		WebsiteConf:         []byte{1, 1, 1, 0, 0, 0, 0xaa},
+		SwiftVersioning:     true,
+		SwiftVerLocation:    "archive",
+		MDSearchConfig:      map[string]uint32{"x-amz-meta-a": 1, "x-amz-meta-b": 2},
+		ReshardStatus:       ReshardInProgress,
+		NewBucketInstanceID: "zone.1234.3",
+		ObjectLock:          []byte{1, 1, 2, 0, 0, 0, 1, 0},
+		SyncPolicy:          []byte{1, 1, 0, 0, 0, 0},
+		Layout: BucketLayout{
+			Resharding: BucketReshardInProgress,
+			CurrentIndex: BucketIndexLayoutGeneration{
+				Gen:    1,
+				Layout: BucketIndexLayout{Normal: BucketIndexNormalLayout{NumShards: 11}},
+			},
+			TargetIndex: &target,
+			Logs: []BucketLogLayoutGeneration{{
+				Gen: 1,
+				Layout: BucketLogLayout{
+					InIndex: BucketIndexLogLayout{Gen: 1, Layout: BucketIndexNormalLayout{NumShards: 11}},
+				},
+			}},
+		},
+	}
+}
+
+func TestEncodeDecodeRGWBucketInfo(t *testing.T) {
+	expected := testBucketInfo()
+	data := EncodeRGWBucketInfo(expected)
+	actual, err := DecodeRGWBucketInfo(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWBucketInfo(actual))
+	assert.Equal(t, "Enabled", actual.Versioning())
+	assert.False(t, actual.MFADelete())
+}
+
+func TestDecodeRGWBucketInfoLegacy(t *testing.T) {
+	// A RGWBucketInfo v9 as written by Hammer: no layout, shard count or
+	// index type, and a quota v1 without max_size.
+	e := NewEncoder()
+	start := e.EncodeStart(9, 4)
+	e.EncodeRGWBucket(&RGWBucket{Name: "bucket", Marker: "default.1", BucketID: "default.1"})
+	e.EncodeString("tenant$user")
+	e.EncodeU32(BucketVersioned | BucketVersionsSuspended)
+	e.EncodeString("default")
+	e.EncodeU64(1400000000)
+	e.EncodeString("default-placement")
+	e.EncodeBool(false)
+	quota := e.EncodeStart(1, 1)
+	e.EncodeI64(4)
+	e.EncodeI64(100)
+	e.EncodeBool(true)
+	e.EncodeFinish(quota)
+	e.EncodeFinish(start)
+
+	info, err := DecodeRGWBucketInfo(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, RGWUser{Tenant: "tenant", ID: "user"}, info.Owner)
+	assert.Equal(t, time.Unix(1400000000, 0).UTC(), info.CreationTime)
+	assert.Equal(t, RGWPlacementRule{Name: "default-placement"}, info.PlacementRule)
+	assert.Equal(t, RGWQuotaInfo{MaxSize: 4096, MaxObjects: 100, Enabled: true}, info.Quota)
+	assert.Equal(t, "Suspended", info.Versioning())
+	assert.Equal(t, BucketLayout{
+		Logs: []BucketLogLayoutGeneration{{Layout: BucketLogLayout{Type: BucketLogInIndex}}},
+	}, info.Layout)
+}
+
+func TestDecodeRGWBucketInfoU32Version(t *testing.T) {
+	// Bucket instances older than v4 start with a u32 version and have no
+	// compat version or length.
+	e := NewEncoder()
+	e.EncodeU32(3)
+	e.EncodeRGWBucket(&RGWBucket{Name: "bucket", Marker: "zone.1.1", BucketID: "zone.1.1"})
+	e.EncodeString("user")
+	e.EncodeU32(BucketSuspended)
+
+	info, err := DecodeRGWBucketInfo(e.Bytes(), DecodeOptions{ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, RGWBucket{Name: "bucket", Marker: "zone.1.1", BucketID: "zone.1.1"}, info.Bucket)
+	assert.Equal(t, RGWUser{ID: "user"}, info.Owner)
+	assert.Equal(t, uint32(BucketSuspended), info.Flags)
+}
+
+func TestDecodeRGWQuotaInfoV2(t *testing.T) {
+	// check_on_raw only exists since v3 and defaults to false.
+	e := NewEncoder()
+	start := e.EncodeStart(2, 1)
+	e.EncodeI64(-1)
+	e.EncodeI64(1000)
+	e.EncodeBool(true)
+	e.EncodeI64(1 << 30)
+	e.EncodeFinish(start)
+
+	d := NewDecoder(e.Bytes(), DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	q, err := d.DecodeRGWQuotaInfo()
+	assert.NoError(t, err)
+	assert.NoError(t, d.Done())
+	assert.Equal(t, &RGWQuotaInfo{MaxSize: 1 << 30, MaxObjects: 1000, Enabled: true}, q)
+	assert.False(t, q.CheckOnRaw)
+}
+
+func TestDecodeBucketLayoutV1(t *testing.T) {
+	e := NewEncoder()
+	start := e.EncodeStart(1, 1)
+	e.EncodeU8(uint8(BucketReshardNone))
+	e.EncodeBucketIndexLayoutGeneration(&BucketIndexLayoutGeneration{
+		Gen:    3,
+		Layout: BucketIndexLayout{Normal: BucketIndexNormalLayout{NumShards: 7}},
+	})
+	e.EncodeBool(false)
+	e.EncodeFinish(start)
+
+	d := NewDecoder(e.Bytes())
+	l, err := d.DecodeBucketLayout()
+	assert.NoError(t, err)
+	assert.Nil(t, l.TargetIndex)
+	assert.Equal(t, []BucketLogLayoutGeneration{{
+		Layout: BucketLogLayout{InIndex: BucketIndexLogLayout{Gen: 3, Layout: BucketIndexNormalLayout{NumShards: 7}}},
+	}}, l.Logs)
+}
+
+func TestRGWUser(t *testing.T) {
+	testcases := []struct {
+		s    string
+		user RGWUser
+	}{
+		{"user", RGWUser{ID: "user"}},
+		{"tenant$user", RGWUser{Tenant: "tenant", ID: "user"}},
+		{"tenant$ns$user", RGWUser{Tenant: "tenant", NS: "ns", ID: "user"}},
+		{"$ns$user", RGWUser{NS: "ns", ID: "user"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.user, parseRGWUser(tt.s))
+			assert.Equal(t, tt.s, tt.user.String())
+		})
+	}
+}
+
+func TestRGWBucketInfoJSONLayout(t *testing.T) {
+	info := &RGWBucketInfo{
+		Bucket: RGWBucket{Name: "bucket"},
+		Owner:  RGWUser{Tenant: "tenant", ID: "user"},
+		Quota:  RGWQuotaInfo{MaxSize: -1, MaxObjects: -1},
+	}
+	out, err := json.Marshal(info)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), `{"bucket":{"name":"bucket",`), "%s", out)
+	assert.Contains(t, string(out), `"owner":"tenant$user"`)
+	assert.Contains(t, string(out), `"quota":{"enabled":false,"check_on_raw":false,"max_size":-1,"max_size_kb":0,"max_objects":-1}`)
+	assert.Contains(t, string(out), `"layout":{"resharding":"None","current_index":{"gen":0,"layout":{"type":"Normal","normal":{"num_shards":0,"hash_type":"Mod"}}},"logs":[]}`)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Input formats accepted by import.
+const (
+	formatAuto    = "auto"
+	formatRaw     = "raw"
+	formatHex     = "hex"
+	formatBase64  = "base64"
+	formatHexdump = "hexdump"
+)
+
+// parseInput converts the imported bytes to the binary encoding according to
+// format. formatAuto guesses the format from the content.
+func parseInput(data []byte, format string) ([]byte, error) {
+	switch format {
+	case formatRaw:
+		return data, nil
+	case formatHex:
+		return parseHex(data)
+	case formatBase64:
+		return parseBase64(data)
+	case formatHexdump:
+		return parseHexdump(data)
+	case formatAuto:
+		if isHexdump(data) {
+			return parseHexdump(data)
+		}
+		if b, err := parseHex(data); err == nil {
+			return b, nil
+		}
+		if b, err := parseBase64(data); err == nil && len(bytes.TrimSpace(data)) > 0 {
+			return b, nil
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+func parseHex(data []byte) ([]byte, error) {
+	s := strings.Join(strings.Fields(string(data)), "")
+	s = strings.TrimPrefix(s, "0x")
+	if s == "" {
+		return nil, fmt.Errorf("empty hex input")
+	}
+	return hex.DecodeString(s)
+}
+
+func parseBase64(data []byte) ([]byte, error) {
+	s := strings.Join(strings.Fields(string(data)), "")
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// isHexdump reports whether data looks like the output of `rados getomapval`
+// or `rados getxattr ... | hexdump -C`.
+func isHexdump(data []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || isHexdumpHeader(line) {
+			continue
+		}
+		off, _, ok := splitHexdumpLine(line)
+		return ok && off == 0
+	}
+	return false
+}
+
+// isHexdumpHeader matches the "value (175 bytes) :" line printed by
+// `rados getomapval`.
+func isHexdumpHeader(line string) bool {
+	return strings.HasPrefix(line, "value (") && strings.HasSuffix(line, ":")
+}
+
+func splitHexdumpLine(line string) (uint64, string, bool) {
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields[0]) != 8 {
+		return 0, "", false
+	}
+	off, err := strconv.ParseUint(fields[0], 16, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if len(fields) == 1 {
+		return off, "", true
+	}
+	rest := fields[1]
+	if i := strings.IndexByte(rest, '|'); i >= 0 {
+		rest = rest[:i]
+	}
+	return off, rest, true
+}
+
+// parseHexdump decodes canonical hexdump output, as printed by hexdump -C and
+// Ceph's bufferlist::hexdump. A "*" line repeats the previous line up to the
+// offset of the next one.
+func parseHexdump(data []byte) ([]byte, error) {
+	var (
+		out    []byte
+		prev   []byte
+		repeat bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || isHexdumpHeader(line) {
+			continue
+		}
+		if line == "*" {
+			repeat = true
+			continue
+		}
+		off, rest, ok := splitHexdumpLine(line)
+		if !ok {
+			return nil, fmt.Errorf("hexdump line %d: bad offset", n)
+		}
+		if repeat {
+			if len(prev) == 0 {
+				return nil, fmt.Errorf("hexdump line %d: nothing to repeat", n)
+			}
+			for uint64(len(out)) < off {
+				out = append(out, prev...)
+			}
+			repeat = false
+		}
+		if off != uint64(len(out)) {
+			return nil, fmt.Errorf("hexdump line %d: offset %#x, expected %#x", n, off, len(out))
+		}
+		b, err := hex.DecodeString(strings.Join(strings.Fields(rest), ""))
+		if err != nil {
+			return nil, fmt.Errorf("hexdump line %d: %v", n, err)
+		}
+		out = append(out, b...)
+		prev = b
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
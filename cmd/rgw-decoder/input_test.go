@@ -0,0 +1,110 @@
+// nolint: scopelint, lll
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// canonicalHexdump formats data like `hexdump -C`, collapsing repeated lines
+// into "*".
+func canonicalHexdump(data []byte) string {
+	var b strings.Builder
+	var prev []byte
+	star := false
+	for off := 0; off < len(data); off += 16 {
+		end := off + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[off:end]
+		if prev != nil && bytes.Equal(line, prev) && len(line) == 16 {
+			if !star {
+				b.WriteString("*\n")
+				star = true
+			}
+			continue
+		}
+		star = false
+		prev = line
+		b.WriteString(strings.Repeat("0", 8-len(hexOffset(off))) + hexOffset(off) + " ")
+		for _, c := range line {
+			b.WriteString(" " + hex.EncodeToString([]byte{c}))
+		}
+		b.WriteString("  |................|\n")
+	}
+	b.WriteString(strings.Repeat("0", 8-len(hexOffset(len(data)))) + hexOffset(len(data)) + "\n")
+	return b.String()
+}
+
+func hexOffset(off int) string {
+	return strings.TrimLeft(hex.EncodeToString([]byte{byte(off >> 24), byte(off >> 16), byte(off >> 8), byte(off)}), "0")
+}
+
+func TestParseInput(t *testing.T) {
+	data, err := ioutil.ReadFile("../../testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	repeated := append(bytes.Repeat([]byte{0xaa}, 64), 1, 2, 3)
+
+	testcases := []struct {
+		name   string
+		input  string
+		format string
+		expect []byte
+	}{
+		{"raw", string(data), formatAuto, data},
+		{"hex", hex.EncodeToString(data) + "\n", formatAuto, data},
+		{"base64", base64.StdEncoding.EncodeToString(data) + "\n", formatAuto, data},
+		{"hexdump", canonicalHexdump(data), formatAuto, data},
+		{"getomapval", "value (175 bytes) :\n" + canonicalHexdump(data), formatAuto, data},
+		{"hexdump repeat", canonicalHexdump(repeated), formatHexdump, repeated},
+		{"forced raw", "abcd", formatRaw, []byte("abcd")},
+		{"forced base64", "abcd", formatBase64, []byte{0x69, 0xb7, 0x1d}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := parseInput([]byte(tt.input), tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, out)
+		})
+	}
+
+	_, err = parseInput([]byte("00000000  zz\n"), formatHexdump)
+	assert.Error(t, err)
+	_, err = parseInput(data, "yaml")
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	s := &state{format: formatAuto, stdout: &out}
+	err := s.run([]string{"import", "../../testdata/manifest_2", "rados-keys"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(out.String(), "\n"))
+
+	out.Reset()
+	data, err := ioutil.ReadFile("../../testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	s = &state{format: formatAuto, stdout: &out, stdin: strings.NewReader(hex.EncodeToString(data))}
+	err = s.run([]string{"type", "cls_user_bucket_entry", "import", "-", "decode", "dump_json"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "{\n    \"bucket\": {\n        \"name\": \"gzim\","), out.String())
+
+	out.Reset()
+	s = &state{format: formatAuto, stdout: &out}
+	err = s.run([]string{"type", "RGWObjManifest", "import", "../../testdata/manifest_2", "explain"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "00000000  07 06 "), out.String())
+	assert.Contains(t, out.String(), "RGWObjManifest.obj_size = ")
+
+	s = &state{format: formatAuto, stdout: &out}
+	assert.Error(t, s.run([]string{"import", "../../testdata/manifest_2", "decode"}))
+	assert.Error(t, s.run([]string{"type", "cls_user_bucket", "rados-keys"}))
+	assert.Error(t, s.run([]string{"frobnicate"}))
+}
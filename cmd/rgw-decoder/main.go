@@ -0,0 +1,214 @@
+// Command rgw-decoder decodes RGW data structures without needing the Ceph
+// packages. Its interface follows ceph-dencoder:
+//
+//	rgw-decoder list_types
+//	rgw-decoder type RGWObjManifest import manifest.bin decode dump_json
+//	rgw-decoder type RGWObjManifest import manifest.bin explain
+//	rados -p default.rgw.buckets.data getxattr <oid> user.rgw.manifest | \
+//		rgw-decoder type RGWObjManifest import - decode rados-keys
+//
+// Input may be raw binary, hex, base64 or the hexdump printed by
+// `rados getomapval`; use -input to force a format instead of guessing it.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	decoder "github.com/liuerfire/rgw-decoder"
+)
+
+const usage = `usage: rgw-decoder [-input auto|raw|hex|base64|hexdump] <command> [<command> ...]
+
+commands:
+  list_types          list the supported types
+  type <name>         select the type to decode
+  import <file|->     read the encoded value from file, or stdin for -
+  decode              decode the imported value as the selected type
+  dump_json           print the decoded value as JSON
+  rados-keys          print the RADOS objects of a decoded RGWObjManifest
+  explain             decode the imported value and print an annotated hexdump
+  explain_json        like explain, but print the fields as JSON
+`
+
+type state struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	format string
+	typ    string
+	data   []byte
+	value  interface{}
+}
+
+func main() {
+	flags := flag.NewFlagSet("rgw-decoder", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	format := flags.String("input", formatAuto, "input format")
+	_ = flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	s := &state{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		format: *format,
+	}
+	if err := s.run(flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (s *state) run(args []string) error {
+	for len(args) > 0 {
+		cmd := args[0]
+		args = args[1:]
+		switch cmd {
+		case "list_types":
+			for _, t := range decoder.ListTypes() {
+				fmt.Fprintln(s.stdout, t)
+			}
+		case "type":
+			if len(args) == 0 {
+				return errors.New("type requires a type name")
+			}
+			s.typ = args[0]
+			s.value = nil
+			args = args[1:]
+		case "import":
+			if len(args) == 0 {
+				return errors.New("import requires a file name or -")
+			}
+			if err := s.importFile(args[0]); err != nil {
+				return err
+			}
+			args = args[1:]
+		case "decode":
+			if err := s.decode(); err != nil {
+				return err
+			}
+		case "dump_json":
+			if s.value == nil {
+				return errors.New("nothing decoded")
+			}
+			enc := json.NewEncoder(s.stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "    ")
+			if err := enc.Encode(s.value); err != nil {
+				return err
+			}
+		case "rados-keys":
+			if s.value == nil && s.typ == "" {
+				s.typ = "RGWObjManifest"
+				if err := s.decode(); err != nil {
+					return err
+				}
+			}
+			manifest, ok := s.value.(*decoder.RGWObjManifest)
+			if !ok {
+				return fmt.Errorf("rados-keys needs a decoded RGWObjManifest, have %q", s.typ)
+			}
+			// RadosObjectsKeys advances the manifest's iterator, so work on
+			// a copy to keep the decoded value intact for later commands.
+			m := *manifest
+			for _, k := range m.RadosObjectsKeys() {
+				fmt.Fprintln(s.stdout, k)
+			}
+		case "explain", "explain_json":
+			if err := s.explain(cmd == "explain_json"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+		}
+	}
+	return nil
+}
+
+func (s *state) importFile(name string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if name == "-" {
+		data, err = ioutil.ReadAll(s.stdin)
+	} else {
+		data, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return err
+	}
+	s.data, err = parseInput(data, s.format)
+	if err != nil {
+		return fmt.Errorf("import %s: %v", name, err)
+	}
+	s.value = nil
+	return nil
+}
+
+func (s *state) decode() error {
+	if s.typ == "" {
+		return errors.New("must specify type")
+	}
+	if s.data == nil {
+		return errors.New("must import a value first")
+	}
+	v, err := decoder.Decode(s.typ, s.data, s.options())
+	if err != nil {
+		return err
+	}
+	s.value = v
+	return nil
+}
+
+// explain decodes the imported value and prints every field read. The fields
+// read before a decode error are printed as well, they show where the input
+// went wrong.
+func (s *state) explain(asJSON bool) error {
+	if s.typ == "" {
+		return errors.New("must specify type")
+	}
+	if s.data == nil {
+		return errors.New("must import a value first")
+	}
+	var e decoder.Explanation
+	opts := s.options()
+	opts.Explain = &e
+	v, decodeErr := decoder.Decode(s.typ, s.data, opts)
+	if asJSON {
+		enc := json.NewEncoder(s.stdout)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&e); err != nil {
+			return err
+		}
+	} else if err := e.WriteHexdump(s.stdout); err != nil {
+		return err
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	s.value = v
+	return nil
+}
+
+// options returns the options of every decode, which print warnings to
+// stderr.
+func (s *state) options() decoder.DecodeOptions {
+	var opts decoder.DecodeOptions
+	if s.stderr != nil {
+		opts.Logger = log.New(s.stderr, "", 0)
+	}
+	return opts
+}
@@ -0,0 +1,126 @@
+package decoder
+
+import "sort"
+
+// DecodeList decodes a std::list or std::vector: a u32 element count followed
+// by the elements, each of which is decoded by fn. The elements show up as
+// name[i] in the field path of errors.
+func (d *Decoder) DecodeList(name string, fn func(i uint32) error) error {
+	d.Field(name)
+	n, err := d.DecodeU32()
+	if err != nil {
+		return err
+	}
+	// Every element takes at least one byte, a larger count can only come
+	// from a corrupted length.
+	if n > d.Remaining() {
+		return d.NewError(&TruncatedError{
+			Offset:    d.Offset,
+			Need:      n,
+			Remaining: d.Remaining(),
+		})
+	}
+	for i := uint32(0); i < n; i++ {
+		d.FieldIndex(name, i)
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeSet decodes a std::set, which is encoded like a list.
+func (d *Decoder) DecodeSet(name string, fn func(i uint32) error) error {
+	return d.DecodeList(name, fn)
+}
+
+// DecodeMap decodes a std::map or std::multimap: a u32 entry count followed
+// by the entries. fn decodes both key and value of the i-th entry.
+func (d *Decoder) DecodeMap(name string, fn func(i uint32) error) error {
+	return d.DecodeList(name, fn)
+}
+
+// DecodePair decodes a std::pair.
+func (d *Decoder) DecodePair(first, second func() error) error {
+	if err := first(); err != nil {
+		return err
+	}
+	return second()
+}
+
+// DecodeOptional decodes a boost::optional or std::optional: a bool telling
+// whether a value follows. fn is only called when it does.
+func (d *Decoder) DecodeOptional(fn func() error) (bool, error) {
+	present, err := d.DecodeBool()
+	if err != nil || !present {
+		return false, err
+	}
+	return true, fn()
+}
+
+func (d *Decoder) DecodeStringList(name string) ([]string, error) {
+	var l []string
+	err := d.DecodeList(name, func(i uint32) error {
+		s, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		l = append(l, s)
+		return nil
+	})
+	return l, err
+}
+
+func (d *Decoder) DecodeStringSet(name string) ([]string, error) {
+	return d.DecodeStringList(name)
+}
+
+func (d *Decoder) DecodeStringMap(name string) (map[string]string, error) {
+	m := make(map[string]string)
+	err := d.DecodeMap(name, func(i uint32) error {
+		k, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		v, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		m[k] = v
+		return nil
+	})
+	return m, err
+}
+
+func (e *Encoder) EncodeList(n int, fn func(i int)) {
+	e.EncodeU32(uint32(n))
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+}
+
+func (e *Encoder) EncodeOptional(present bool, fn func()) {
+	e.EncodeBool(present)
+	if present {
+		fn()
+	}
+}
+
+func (e *Encoder) EncodeStringList(l []string) {
+	e.EncodeList(len(l), func(i int) {
+		e.EncodeString(l[i])
+	})
+}
+
+// EncodeStringMap encodes m with its keys in sorted order, like std::map.
+func (e *Encoder) EncodeStringMap(m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.EncodeList(len(keys), func(i int) {
+		e.EncodeString(keys[i])
+		e.EncodeString(m[keys[i]])
+	})
+}
@@ -0,0 +1,103 @@
+package decoder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeContainers(t *testing.T) {
+	ts := time.Date(2020, 8, 22, 10, 0, 0, 123456789, time.UTC)
+	uuid := UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+	e := NewEncoder()
+	e.EncodeStringList([]string{"a", "b"})
+	e.EncodeStringMap(map[string]string{"k2": "v2", "k1": "v1"})
+	e.EncodeOptional(true, func() {
+		e.EncodeI32(-1)
+	})
+	e.EncodeOptional(false, nil)
+	e.EncodeString("first")
+	e.EncodeI64(-2)
+	e.EncodeBufferlist([]byte{1, 2, 3})
+	e.EncodeRealTime(ts)
+	e.EncodeUTime(time.Time{})
+	e.EncodeUUID(uuid)
+	e.EncodeU16(0xbeef)
+
+	d := NewDecoder(e.Bytes())
+	l, err := d.DecodeStringList("list")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, l)
+
+	m, err := d.DecodeStringMap("map")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, m)
+
+	var i32 int32
+	present, err := d.DecodeOptional(func() error {
+		var err error
+		i32, err = d.DecodeI32()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Equal(t, int32(-1), i32)
+
+	present, err = d.DecodeOptional(func() error {
+		t.Fatal("absent optional decoded")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, present)
+
+	var first string
+	var second int64
+	err = d.DecodePair(func() error {
+		var err error
+		first, err = d.DecodeString()
+		return err
+	}, func() error {
+		var err error
+		second, err = d.DecodeI64()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", first)
+	assert.Equal(t, int64(-2), second)
+
+	bl, err := d.DecodeBufferlist()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, bl)
+
+	rt, err := d.DecodeRealTime()
+	assert.NoError(t, err)
+	assert.Equal(t, ts, rt)
+
+	ut, err := d.DecodeUTime()
+	assert.NoError(t, err)
+	assert.True(t, ut.IsZero())
+
+	u, err := d.DecodeUUID()
+	assert.NoError(t, err)
+	assert.Equal(t, uuid, u)
+	assert.Equal(t, "12345678-9abc-def0-0123-456789abcdef", u.String())
+
+	u16, err := d.DecodeU16()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0xbeef), u16)
+	assert.Equal(t, uint32(0), d.Remaining())
+}
+
+func TestDecodeListHugeCount(t *testing.T) {
+	d := NewDecoder([]byte{0xff, 0xff, 0xff, 0xff, 0x00})
+	called := false
+	err := d.DecodeList("list", func(i uint32) error {
+		called = true
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrTruncated))
+	assert.False(t, called)
+}
@@ -0,0 +1,50 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var corpus = flag.String("corpus", "", "directory in the ceph-object-corpus layout, archive/<version>/objects/<type>/<blob>, to decode")
+
+// TestCorpus decodes the blobs Ceph wrote for every registered type found
+// in -corpus, and compares their JSON with ceph-dencoder when it is
+// installed. It is skipped without -corpus: the repository only ships
+// the blobs in testdata.
+func TestCorpus(t *testing.T) {
+	if *corpus == "" {
+		t.Skip("no -corpus directory given")
+	}
+	found := 0
+	for _, typ := range ListTypes() {
+		files, err := filepath.Glob(filepath.Join(*corpus, "archive", "*", "objects", typ, "*"))
+		assert.NoError(t, err)
+		for _, file := range files {
+			found++
+			name, err := filepath.Rel(*corpus, file)
+			assert.NoError(t, err)
+			t.Run(name, func(t *testing.T) {
+				data, err := ioutil.ReadFile(file)
+				assert.NoError(t, err)
+				v, err := Decode(typ, data)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if expected, ok := cephDencoder(t, typ, file); ok {
+					actual, err := json.Marshal(v)
+					assert.NoError(t, err)
+					assert.JSONEq(t, string(expected), string(actual))
+				}
+			})
+		}
+	}
+	if found == 0 {
+		t.Errorf("no blobs of a registered type in %s", *corpus)
+	}
+}
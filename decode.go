@@ -1,128 +1,406 @@
 package decoder
 
 import (
-	"bytes"
 	"encoding/binary"
-	"errors"
+	"fmt"
+	"time"
 )
 
-type decoder struct {
+// UnknownBytes holds the trailing bytes of a struct that were written by a
+// newer encoder and skipped while decoding.
+type UnknownBytes struct {
+	Offset uint32
+	Data   []byte
+}
+
+// UUID is a uuid_d.
+type UUID [16]byte
+
+func (u UUID) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Decoder reads Ceph encoded values from Data, starting at Offset. It is the
+// building block of all decoders in this package and can be used to write
+// decoders for additional types.
+type Decoder struct {
 	Data   []byte
 	Offset uint32
+
+	opts      DecodeOptions
+	spanDepth int
+	skipped   uint32
+	unknown   []UnknownBytes
+	warnings  []Warning
+	frames    []frame
+	// frameBuf backs frames for the usual nesting depth, saving the
+	// allocations of growing it.
+	frameBuf [8]frame
 }
 
-func (d *decoder) readNextBytes(octlen uint32) []byte {
+// NewDecoder returns a Decoder that reads data from the beginning. At most
+// one DecodeOptions may be given.
+func NewDecoder(data []byte, opts ...DecodeOptions) *Decoder {
+	d := &Decoder{
+		Data: data,
+	}
+	d.frames = d.frameBuf[:0]
+	if len(opts) > 0 {
+		d.opts = opts[0]
+	}
+	return d
+}
+
+// ReadBytes consumes the next octlen bytes. The returned slice aliases Data.
+func (d *Decoder) ReadBytes(octlen uint32) ([]byte, error) {
+	remaining := d.Remaining()
+	if octlen > remaining {
+		return nil, d.NewError(&TruncatedError{
+			Offset:    d.Offset,
+			Need:      octlen,
+			Remaining: remaining,
+		})
+	}
 	offset := d.Offset
 	d.Offset += octlen
-	return d.Data[offset:d.Offset]
+	return d.Data[offset:d.Offset], nil
 }
 
-func (d *decoder) getRemaining() uint32 {
+// Remaining returns the number of bytes left to decode.
+func (d *Decoder) Remaining() uint32 {
+	if uint32(len(d.Data)) < d.Offset {
+		return 0
+	}
 	return uint32(len(d.Data)) - d.Offset
 }
 
-func (d *decoder) decodeU8() uint8 {
-	return d.readNextBytes(1)[0]
+func (d *Decoder) DecodeU8() (v uint8, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	b, err := d.ReadBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
 }
 
-func (d *decoder) decodeBool() bool {
-	return d.readNextBytes(1)[0] != 0
+func (d *Decoder) DecodeBool() (v bool, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	b, err := d.DecodeU8()
+	return b != 0, err
 }
 
-func (d *decoder) decodeU32() (uint32, error) {
-	var re uint32
-	buffer := bytes.NewBuffer(d.readNextBytes(4))
-	err := binary.Read(buffer, binary.LittleEndian, &re)
-	return re, err
+// DecodeU16 decodes a uint16 or ceph_le16. Like all integer decoders it
+// reads little endian, so it serves the ceph_le types as well.
+func (d *Decoder) DecodeU16() (v uint16, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	b, err := d.ReadBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
 }
 
-func (d *decoder) decodeU64() (uint64, error) {
-	var re uint64
-	buffer := bytes.NewBuffer(d.readNextBytes(8))
-	err := binary.Read(buffer, binary.LittleEndian, &re)
-	return re, err
+func (d *Decoder) DecodeU32() (v uint32, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	b, err := d.ReadBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
 }
 
-func (d *decoder) decodeString() (string, error) {
-	strLen, err := d.decodeU32()
+func (d *Decoder) DecodeU64() (v uint64, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	b, err := d.ReadBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (d *Decoder) DecodeString() (v string, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	strLen, err := d.DecodeU32()
 	if err != nil {
 		return "", err
 	}
-	offset := d.Offset
-	d.Offset += strLen
-	return string(d.Data[offset:d.Offset]), err
+	b, err := d.ReadBytes(strLen)
+	if err != nil {
+		return "", err
+	}
+	if d.opts.Strings != nil {
+		return d.opts.Strings.intern(b), nil
+	}
+	return string(b), nil
 }
 
-func (d *decoder) decodeTime() (s uint32, ns uint32, err error) {
-	s, err = d.decodeU32()
+func (d *Decoder) DecodeTime() (s uint32, ns uint32, err error) {
+	s, err = d.DecodeU32()
 	if err != nil {
 		return
 	}
-	ns, err = d.decodeU32()
+	ns, err = d.DecodeU32()
 	return
 }
 
-func (d *decoder) decodeStart(v int) (structV uint8, structLen uint32, structEnd uint32, err error) {
-	structV = d.decodeU8()
-	structCompat := d.decodeU8()
-	if v < int(structCompat) {
-		err = errors.New("DECODE_ERR_OLDVERSION")
-		return
+func (d *Decoder) DecodeI32() (v int32, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	u, err := d.DecodeU32()
+	return int32(u), err
+}
+
+func (d *Decoder) DecodeI64() (v int64, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
 	}
-	structLen, err = d.decodeU32()
+	u, err := d.DecodeU64()
+	return int64(u), err
+}
+
+// DecodeBufferlist decodes a nested bufferlist: a u32 length followed by the
+// raw bytes. The returned slice aliases Data.
+func (d *Decoder) DecodeBufferlist() (v []byte, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	l, err := d.DecodeU32()
+	if err != nil {
+		return nil, err
+	}
+	return d.ReadBytes(l)
+}
+
+// DecodeRealTime decodes a ceph::real_time. The zero encoding yields the
+// zero time.Time.
+func (d *Decoder) DecodeRealTime() (v time.Time, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	s, ns, err := d.DecodeTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timeFromParts(s, ns), nil
+}
+
+// DecodeUTime decodes a utime_t, which shares the wire format of
+// ceph::real_time.
+func (d *Decoder) DecodeUTime() (time.Time, error) {
+	return d.DecodeRealTime()
+}
+
+// DecodeUUID decodes a uuid_d.
+func (d *Decoder) DecodeUUID() (v UUID, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() { d.endSpan(start, v, nil, err) }()
+	}
+	var u UUID
+	b, err := d.ReadBytes(uint32(len(u)))
+	if err != nil {
+		return u, err
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// DecodeStart reads a DECODE_START header of the Ceph type typ that this
+// decoder understands up to version v. It returns the encoded struct version,
+// its length and the offset where it ends, which must be passed to
+// DecodeFinish.
+func (d *Decoder) DecodeStart(typ string, v int) (structV uint8, structLen uint32, structEnd uint32, err error) {
+	d.pushFrame(typ)
+	structV, _, structLen, _, err = d.decodeHeader(d.maxVersion(typ, uint32(v)), 0, 0, 0)
 	if err != nil {
 		return
 	}
-	if structLen > d.getRemaining() {
-		err = errors.New("DECODE_ERR_PAST")
+	if structLen > d.Remaining() {
+		err = d.NewError(ErrPastEnd)
 		return
 	}
 	structEnd = d.Offset + structLen
 	return
 }
 
-func (d *decoder) decodeStartLegacyCompatLen(v, compactV, lenv uint32) (structV uint8, structEnd uint32, err error) {
-	var structLen uint32
+// DecodeStartLegacyCompatLen reads a DECODE_START_LEGACY_COMPAT_LEN header.
+// Encodings older than compactV carry no compat version and encodings older
+// than lenv carry no length, in which case the returned struct end is 0.
+func (d *Decoder) DecodeStartLegacyCompatLen(typ string, v, compactV, lenv uint32) (structV uint8, structEnd uint32, err error) {
+	return d.decodeStartLegacy(typ, v, compactV, lenv, 0)
+}
+
+// DecodeStartLegacyCompatLen32 reads a DECODE_START_LEGACY_COMPAT_LEN_32
+// header, whose encodings older than compactV have a u32 version of which
+// the three upper bytes are skipped.
+func (d *Decoder) DecodeStartLegacyCompatLen32(typ string, v, compactV, lenv uint32) (structV uint8, structEnd uint32, err error) {
+	return d.decodeStartLegacy(typ, v, compactV, lenv, 3)
+}
 
-	cv := d.decodeU8()
+func (d *Decoder) decodeStartLegacy(typ string, v, compactV, lenv, skipV uint32) (structV uint8, structEnd uint32, err error) {
+	d.pushFrame(typ)
+	structV, _, structLen, hasLen, err := d.decodeHeader(d.maxVersion(typ, v), compactV, lenv, skipV)
 	if err != nil {
 		return
 	}
-	structV = cv
-	if uint32(cv) >= compactV {
-		sCompact := d.decodeU8()
-		if v < uint32(sCompact) {
-			err = errors.New("DECODE_ERR_OLDVERSION")
+	if hasLen {
+		if structLen > d.Remaining() {
+			err = d.NewError(ErrPastEnd)
 			return
 		}
+		structEnd = d.Offset + structLen
+	}
+	return
+}
+
+// decodeHeader reads the version, compat version and length of a struct
+// header and fails if the compat version is above v, or in strict mode the
+// version itself. Like
+// DecodeStartLegacyCompatLen, versions below compactV have no compat version
+// but skipV bytes of padding and versions below lenv have no length; a plain
+// DECODE_START header is read with all of them set to 0.
+func (d *Decoder) decodeHeader(v, compactV, lenv, skipV uint32) (structV, structCompat uint8, structLen uint32, hasLen bool, err error) {
+	if d.explaining() {
+		start := d.beginSpan()
+		defer func() {
+			d.endSpan(start, nil, &StructHeader{
+				Type:    d.frames[len(d.frames)-1].typ,
+				Version: structV,
+				Compat:  structCompat,
+				Len:     structLen,
+				HasLen:  hasLen,
+			}, err)
+		}()
 	}
-	structEnd = 0
-	if uint32(cv) >= lenv {
-		structLen, err = d.decodeU32()
+	structV, err = d.DecodeU8()
+	if err != nil {
+		return
+	}
+	d.setFrameVersion(structV, 0)
+	if uint32(structV) >= compactV {
+		structCompat, err = d.DecodeU8()
 		if err != nil {
 			return
 		}
-		if structLen > d.getRemaining() {
-			err = errors.New("DECODE_ERR_PAST")
+		d.setFrameVersion(structV, structCompat)
+		if v < uint32(structCompat) {
+			err = d.NewError(ErrOldVersion)
 			return
 		}
-		structEnd = d.Offset + structLen
+	} else if skipV > 0 {
+		if skipV > d.Remaining() {
+			err = d.NewError(ErrPastEnd)
+			return
+		}
+		d.Offset += skipV
+	}
+	if d.opts.Mode == ModeStrict && uint32(structV) > v {
+		err = d.NewError(ErrNewVersion)
+		return
+	}
+	if uint32(structV) >= lenv {
+		structLen, err = d.DecodeU32()
+		hasLen = err == nil
 	}
 	return
 }
 
-func (d *decoder) decodeFinish(structEnd uint32) error {
+// DecodeFinish ends a struct started with DecodeStart or
+// DecodeStartLegacyCompatLen. Fields appended by newer encoders are skipped
+// and recorded, see Skipped and Unknown, except in strict mode where they
+// fail with ErrLeftover.
+func (d *Decoder) DecodeFinish(structEnd uint32) error {
 	if structEnd > 0 {
 		if d.Offset > structEnd {
-			return errors.New("DECODE_ERR_PAST")
+			return d.NewError(ErrPastEnd)
+		}
+		if d.Offset < structEnd {
+			if d.opts.Mode == ModeStrict {
+				return d.NewError(ErrLeftover)
+			}
+			d.Field("")
+			if d.explaining() {
+				d.addSpan(Span{
+					Offset:  d.Offset,
+					Raw:     d.Data[d.Offset:structEnd],
+					Unknown: true,
+				})
+			}
+			d.Warn(WarnUnknownFields, "skipped %d bytes of unknown fields", structEnd-d.Offset)
+			d.unknown = append(d.unknown, UnknownBytes{
+				Offset: d.Offset,
+				Data:   d.Data[d.Offset:structEnd],
+			})
+			d.skipped += structEnd - d.Offset
+			d.Offset = structEnd
 		}
 	}
+	d.popFrame()
 	return nil
 }
 
-func DecodeAccessKey(data []byte) (string, error) {
-	d := &decoder{
-		Data: data,
+// DecodeRawStruct skips a struct of the Ceph type typ that starts with a
+// DECODE_START header and returns its encoding, header included. It serves
+// nested structs whose fields are not decoded.
+func (d *Decoder) DecodeRawStruct(typ string) ([]byte, error) {
+	start := d.Offset
+	_, _, structEnd, err := d.DecodeStart(typ, 255)
+	if err != nil {
+		return nil, err
+	}
+	if d.explaining() {
+		d.addSpan(Span{
+			Offset: d.Offset,
+			Raw:    d.Data[d.Offset:structEnd],
+		})
+	}
+	d.Offset = structEnd
+	d.popFrame()
+	return d.Data[start:structEnd], nil
+}
+
+// Skipped returns the number of unknown trailing struct bytes skipped so far.
+func (d *Decoder) Skipped() uint32 {
+	return d.skipped
+}
+
+// Unknown returns the unknown trailing struct bytes skipped so far.
+func (d *Decoder) Unknown() []UnknownBytes {
+	return d.unknown
+}
+
+// DecodeAccessKey returns the user string of a user index object.
+//
+// Deprecated: DecodeRGWUID also handles versioned index objects and splits
+// the tenant and namespace off the user id.
+func DecodeAccessKey(data []byte, opts ...DecodeOptions) (string, error) {
+	d := NewDecoder(data, opts...)
+	key, err := d.DecodeString()
+	if err != nil {
+		return "", err
 	}
-	return d.decodeString()
+	return key, d.Done()
 }
@@ -3,35 +3,69 @@ package decoder
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"io/ioutil"
 	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-type bucketEntry struct {
-	Bucket struct {
-		Name     string `json:"name,omitempty"`
-		Marker   string `json:"marker,omitempty"`
-		BucketID string `json:"bucket_id,omitempty"`
-	} `json:"bucket,omitempty"`
-	Size        uint64 `json:"size,omitempty"`
-	SizeRounded uint64 `json:"size_rounded,omitempty"`
-	Count       uint64 `json:"count,omitempty"`
-}
+var update = flag.Bool("update", false, "regenerate the golden JSON files in testdata with ceph-dencoder")
 
-func cephDecoderBucketEntry(file string) *bucketEntry {
-	cmd := exec.Command("ceph-dencoder", "type", "cls_user_bucket_entry", "import", file, "decode", "dump_json")
-	output, err := cmd.CombinedOutput()
+// cephDencoder returns the dump_json output of ceph-dencoder for file. The
+// second result is false when ceph-dencoder is not installed.
+func cephDencoder(t *testing.T, typ, file string) ([]byte, bool) {
+	if _, err := exec.LookPath("ceph-dencoder"); err != nil {
+		return nil, false
+	}
+	output, err := exec.Command("ceph-dencoder", "type", typ, "import", file, "decode", "dump_json").CombinedOutput()
 	if err != nil {
-		panic(err)
+		t.Fatalf("ceph-dencoder: %v: %s", err, output)
 	}
-	var u bucketEntry
-	if err = json.Unmarshal(output, &u); err != nil {
-		panic(err)
+	return output, true
+}
+
+// TestGolden compares the JSON of every testdata blob with the golden file
+// stored next to it. Run with -update on a host with ceph-dencoder to
+// regenerate the golden files from its dump_json output. The files checked
+// in were written by this package and have not been regenerated with
+// ceph-dencoder yet.
+func TestGolden(t *testing.T) {
+	testcases := []struct {
+		file string
+		typ  string
+	}{
+		{"testdata/bucket_entry_1", "cls_user_bucket_entry"},
+		{"testdata/manifest_1", "RGWObjManifest"},
+		{"testdata/manifest_2", "RGWObjManifest"},
+		{"testdata/manifest_3", "RGWObjManifest"},
+		{"testdata/manifest_4", "RGWObjManifest"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
+			v, err := Decode(tt.typ, data)
+			assert.NoError(t, err)
+			actual, err := json.Marshal(v)
+			assert.NoError(t, err)
+
+			golden := tt.file + ".json"
+			if *update {
+				expected, ok := cephDencoder(t, tt.typ, tt.file)
+				if !ok {
+					t.Fatal("-update needs ceph-dencoder in PATH")
+				}
+				assert.NoError(t, ioutil.WriteFile(golden, expected, 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
 	}
-	return &u
 }
 
 func TestDecodeUserBucketEntry(t *testing.T) {
@@ -43,68 +77,23 @@ func TestDecodeUserBucketEntry(t *testing.T) {
 		},
 	}
 	for _, tt := range testcases {
-		expectedBucketEntry := cephDecoderBucketEntry(tt.file)
+		t.Run(tt.file, func(t *testing.T) {
+			expected, ok := cephDencoder(t, "cls_user_bucket_entry", tt.file)
+			if !ok {
+				t.Skip("ceph-dencoder not found in PATH")
+			}
 
-		data, err := ioutil.ReadFile(tt.file)
-		assert.NoError(t, err)
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
 
-		bucketEntry, err := DecodeUserBucketEntry(data)
-		assert.NoError(t, err)
+			bucketEntry, err := DecodeUserBucketEntry(data)
+			assert.NoError(t, err)
 
-		assert.Equal(t, expectedBucketEntry.Bucket.Marker, bucketEntry.Bucket.Marker)
-		assert.Equal(t, expectedBucketEntry.Bucket.BucketID, bucketEntry.Bucket.BucketID)
-	}
-}
-
-type manifest struct {
-	ObjSize     uint64 `json:"obj_size,omitempty"`
-	HeadSize    uint64 `json:"head_size,omitempty"`
-	MaxHeapSize uint64 `json:"max_heap_size,omitempty"`
-	Prefix      string `json:"prefix,omitempty"`
-	Rules       []struct {
-		Key uint64 `json:"key,omitempty"`
-		Val struct {
-			StartPartNum   uint32 `json:"start_part_num,omitempty"`
-			StartOfs       uint64 `json:"start_ofs,omitempty"`
-			PartSize       uint64 `json:"part_size,omitempty"`
-			StripeMaxSize  uint64 `json:"stripe_max_size,omitempty"`
-			OverridePrefix string `json:"override_prefix,omitempty"`
-		} `json:"val,omitempty"`
-	} `json:"rules,omitempty"`
-	BeginIter iter `json:"begin_iter,omitempty"`
-	EndIter   iter `json:"end_iter,omitempty"`
-}
-
-type iter struct {
-	PartOfs           uint64 `json:"part_ofs,omitempty"`
-	StripeOfs         uint64 `json:"stripe_ofs,omitempty"`
-	Ofs               uint64 `json:"ofs,omitempty"`
-	StripeSize        uint64 `json:"stripe_size,omitempty"`
-	CurPartID         int32  `json:"cur_part_id,omitempty"`
-	CurStripe         int32  `json:"cur_stripe,omitempty"`
-	CurOverridePrefix string `json:"cur_override_prefix,omitempty"`
-	Location          struct {
-		Obj struct {
-			Key struct {
-				Name     string `json:"name,omitempty"`
-				Instance string `json:"instance,omitempty"`
-				NS       string `json:"ns,omitempty"`
-			} `json:"key,omitempty"`
-		} `json:"obj,omitempty"`
-	} `json:"location,omitempty"`
-}
-
-func cephDecoderManifest(file string) *manifest {
-	cmd := exec.Command("ceph-dencoder", "type", "RGWObjManifest", "import", file, "decode", "dump_json")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		panic(err)
-	}
-	var m manifest
-	if err = json.Unmarshal(output, &m); err != nil {
-		panic(err)
+			actual, err := json.Marshal(bucketEntry)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
 	}
-	return &m
 }
 
 func TestDecodeRGWObjManifest(t *testing.T) {
@@ -141,45 +130,24 @@ func TestDecodeRGWObjManifest(t *testing.T) {
 	}
 	for _, tt := range testcases {
 		t.Run(tt.file, func(t *testing.T) {
-			expectedManifest := cephDecoderManifest(tt.file)
-
 			data, err := ioutil.ReadFile(tt.file)
 			assert.NoError(t, err)
 
 			manifest, err := DecodeRGWObjManifest(data)
 			assert.NoError(t, err)
+			// Head and tail of every blob are in default-placement, and the
+			// iterator locations keep the placement rule of their part.
+			placement := RGWPlacementRule{Name: "default-placement"}
+			assert.Equal(t, placement, manifest.TailPlacement.PlacementRule)
+			assert.Equal(t, placement, manifest.BeginIter.Location.PlacementRule)
+			assert.Equal(t, placement, manifest.EndIter.Location.PlacementRule)
 
-			assert.Equal(t, expectedManifest.ObjSize, manifest.ObjSize)
-			assert.Equal(t, expectedManifest.Prefix, manifest.Prefix)
-			assert.Equal(t, len(expectedManifest.Rules), len(manifest.Rules))
-
-			for _, rule := range expectedManifest.Rules {
-				assert.Equal(t, rule.Val.StartPartNum, manifest.Rules[rule.Key].StartPartNum)
-				assert.Equal(t, rule.Val.StartOfs, manifest.Rules[rule.Key].StartOfs)
-				assert.Equal(t, rule.Val.PartSize, manifest.Rules[rule.Key].PartSize)
-				assert.Equal(t, rule.Val.StripeMaxSize, manifest.Rules[rule.Key].StripeMaxSize)
+			if expected, ok := cephDencoder(t, "RGWObjManifest", tt.file); ok {
+				actual, err := json.Marshal(manifest)
+				assert.NoError(t, err)
+				assert.JSONEq(t, string(expected), string(actual))
 			}
 
-			assert.Equal(t, expectedManifest.BeginIter.PartOfs, manifest.BeginIter.PartOfs)
-			assert.Equal(t, expectedManifest.BeginIter.StripeOfs, manifest.BeginIter.StripeOfs)
-			assert.Equal(t, expectedManifest.BeginIter.Ofs, manifest.BeginIter.Ofs)
-			assert.Equal(t, expectedManifest.BeginIter.StripeSize, manifest.BeginIter.StripeSize)
-			assert.Equal(t, expectedManifest.BeginIter.CurPartID, manifest.BeginIter.CurPartID)
-			assert.Equal(t, expectedManifest.BeginIter.CurStripe, manifest.BeginIter.CurStripe)
-			assert.Equal(t, expectedManifest.BeginIter.Location.Obj.Key.Name, manifest.BeginIter.Location.Obj.Key.Name)
-			assert.Equal(t, expectedManifest.BeginIter.Location.Obj.Key.Instance, manifest.BeginIter.Location.Obj.Key.Instance)
-			assert.Equal(t, expectedManifest.BeginIter.Location.Obj.Key.NS, manifest.BeginIter.Location.Obj.Key.NS)
-
-			assert.Equal(t, expectedManifest.EndIter.PartOfs, manifest.EndIter.PartOfs)
-			assert.Equal(t, expectedManifest.EndIter.StripeOfs, manifest.EndIter.StripeOfs)
-			assert.Equal(t, expectedManifest.EndIter.Ofs, manifest.EndIter.Ofs)
-			assert.Equal(t, expectedManifest.EndIter.StripeSize, manifest.EndIter.StripeSize)
-			assert.Equal(t, expectedManifest.EndIter.CurPartID, manifest.EndIter.CurPartID)
-			assert.Equal(t, expectedManifest.EndIter.CurStripe, manifest.EndIter.CurStripe)
-			assert.Equal(t, expectedManifest.EndIter.Location.Obj.Key.Name, manifest.EndIter.Location.Obj.Key.Name)
-			assert.Equal(t, expectedManifest.EndIter.Location.Obj.Key.Instance, manifest.EndIter.Location.Obj.Key.Instance)
-			assert.Equal(t, expectedManifest.EndIter.Location.Obj.Key.NS, manifest.EndIter.Location.Obj.Key.NS)
-
 			radosKeys := manifest.RadosObjectsKeys()
 			assert.Equal(t, len(tt.radosKeys), len(radosKeys))
 			for i, k := range tt.radosKeys {
@@ -188,3 +156,387 @@ func TestDecodeRGWObjManifest(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeRGWObjManifestNoRules(t *testing.T) {
+	m := &RGWObjManifest{
+		ObjSize:  8,
+		HeadSize: 4,
+		Obj: RGWObj{
+			Bucket: RGWBucket{Name: "bucket", Marker: "marker"},
+			Key:    RGWObjKey{Name: "obj"},
+		},
+	}
+	decoded, err := DecodeRGWObjManifest(EncodeRGWObjManifest(m))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"marker_obj"}, decoded.RadosObjectsKeys())
+}
+
+func TestRadosObjectsKeysZeroStripe(t *testing.T) {
+	m := &RGWObjManifest{
+		ObjSize:  8,
+		HeadSize: 4,
+		Prefix:   "prefix_",
+		Rules: map[uint64]RGWObjManifestRule{
+			0: {},
+		},
+		Obj: RGWObj{
+			Bucket: RGWBucket{Name: "bucket", Marker: "marker"},
+			Key:    RGWObjKey{Name: "obj"},
+		},
+	}
+	decoded, err := DecodeRGWObjManifest(EncodeRGWObjManifest(m))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"marker_obj", "marker__shadow_prefix_1"}, decoded.RadosObjectsKeys())
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	testcases := []struct {
+		file   string
+		decode func([]byte) error
+	}{
+		{
+			file: "testdata/bucket_entry_1",
+			decode: func(data []byte) error {
+				_, err := DecodeUserBucketEntry(data)
+				return err
+			},
+		},
+		{
+			file: "testdata/manifest_1",
+			decode: func(data []byte) error {
+				_, err := DecodeRGWObjManifest(data)
+				return err
+			},
+		},
+		{
+			file: "testdata/manifest_2",
+			decode: func(data []byte) error {
+				_, err := DecodeRGWObjManifest(data)
+				return err
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
+			for i := 0; i < len(data); i++ {
+				assert.Error(t, tt.decode(data[:i]), "prefix of %d bytes", i)
+			}
+		})
+	}
+}
+
+func TestDecodeStringTruncated(t *testing.T) {
+	_, err := DecodeAccessKey([]byte{0xff, 0xff, 0xff, 0x7f, 'a', 'b'})
+	var te *TruncatedError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, uint32(4), te.Offset)
+	assert.Equal(t, uint32(0x7fffffff), te.Need)
+	assert.Equal(t, uint32(2), te.Remaining)
+
+	_, err = DecodeAccessKey([]byte{0x01, 0x00})
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, uint32(0), te.Offset)
+	assert.Equal(t, uint32(4), te.Need)
+}
+
+func TestDecodeFinishSkipsUnknownFields(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	expected, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+
+	// Pretend a newer encoder appended three bytes to the rgw_bucket nested
+	// in RGWObjManifest.obj and bump every enclosing struct_len accordingly.
+	const (
+		manifestLen = 0x02
+		objLen      = 0x15
+		bucketV     = 0x19
+		bucketLen   = 0x1b
+		bucketEnd   = 0xba
+	)
+	extra := []byte{0xde, 0xad, 0xbf}
+	newer := append([]byte{}, data[:bucketEnd]...)
+	newer = append(newer, extra...)
+	newer = append(newer, data[bucketEnd:]...)
+	newer[bucketV] = 11
+	for _, pos := range []int{manifestLen, objLen, bucketLen} {
+		newer[pos] += byte(len(extra))
+	}
+
+	d := NewDecoder(newer)
+	manifest, err := d.DecodeRGWObjManifest()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Obj, manifest.Obj)
+	assert.Equal(t, expected.Rules, manifest.Rules)
+	assert.Equal(t, expected.TailPlacement, manifest.TailPlacement)
+	assert.Equal(t, uint32(len(newer)), d.Offset)
+	assert.Equal(t, uint32(len(extra)), d.Skipped())
+	assert.Equal(t, []UnknownBytes{{Offset: bucketEnd, Data: extra}}, d.Unknown())
+}
+
+func TestDecodeUserBucketEntryOlderBucket(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+
+	entry, err := DecodeUserBucketEntry(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "gzim", entry.Bucket.Name)
+	assert.Equal(t, "d29b7d7b-87c7-480e-b614-3006673b3f18.56037870.1", entry.Bucket.BucketID)
+	assert.Equal(t, uint64(0x01464ded556f), entry.Size)
+	assert.Equal(t, uint64(0x0147a896c000), entry.SizeRounded)
+	assert.Equal(t, uint64(0x320516), entry.Count)
+	assert.True(t, entry.UserStatusSync)
+}
+
+func TestDecodeUserBucketVersions(t *testing.T) {
+	testcases := []struct {
+		name     string
+		v        uint8
+		fields   []string
+		expected UserBucket
+	}{
+		{
+			name:   "v7",
+			v:      7,
+			fields: []string{"bucket", "data", "zone.1.2", "zone.1.2", "index", "extra"},
+			expected: UserBucket{
+				Name:              "bucket",
+				Marker:            "zone.1.2",
+				BucketID:          "zone.1.2",
+				ExplicitPlacement: explicitPlacement{DataPool: "data", IndexPool: "index", DataExtraPool: "extra"},
+			},
+		},
+		{
+			name:   "v8 explicit placement",
+			v:      8,
+			fields: []string{"bucket", "zone.1.2", "zone.1.2", "", "data", "index", "extra"},
+			expected: UserBucket{
+				Name:              "bucket",
+				Marker:            "zone.1.2",
+				BucketID:          "zone.1.2",
+				ExplicitPlacement: explicitPlacement{DataPool: "data", IndexPool: "index", DataExtraPool: "extra"},
+			},
+		},
+		{
+			name:   "v8 placement id",
+			v:      8,
+			fields: []string{"bucket", "zone.1.2", "zone.1.2", "default-placement"},
+			expected: UserBucket{
+				Name:        "bucket",
+				Marker:      "zone.1.2",
+				BucketID:    "zone.1.2",
+				PlacementID: "default-placement",
+			},
+		},
+		{
+			name:   "v9",
+			v:      9,
+			fields: []string{"bucket", "zone.1.2", "zone.1.2", "default-placement"},
+			expected: UserBucket{
+				Name:        "bucket",
+				Marker:      "zone.1.2",
+				BucketID:    "zone.1.2",
+				PlacementID: "default-placement",
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncoder()
+			start := e.EncodeStart(tt.v, 3)
+			for _, f := range tt.fields {
+				e.EncodeString(f)
+			}
+			e.EncodeFinish(start)
+
+			u, err := DecodeUserBucket(e.Bytes(), DecodeOptions{ErrorOnLeftover: true})
+			assert.NoError(t, err)
+			assert.Equal(t, &tt.expected, u)
+		})
+	}
+}
+
+func TestDecodeUserBucketEntryV8(t *testing.T) {
+	e := NewEncoder()
+	start := e.EncodeStart(8, 5)
+	e.EncodeString("")
+	e.EncodeU64(4096)
+	e.EncodeU32(1500000000)
+	e.EncodeU64(2)
+	e.EncodeUserBucket(&UserBucket{Name: "bucket", Marker: "zone.1.2", BucketID: "zone.1.2", PlacementID: "default-placement"})
+	e.EncodeU64(8192)
+	e.EncodeBool(true)
+	e.EncodeTime(1500000000, 7)
+	e.EncodeString("default-placement")
+	e.EncodeFinish(start)
+
+	entry, err := DecodeUserBucketEntry(e.Bytes(), DecodeOptions{ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &UserBucketEntry{
+		Size:           4096,
+		SizeRounded:    8192,
+		CreationTime:   time.Unix(1500000000, 7).UTC(),
+		Count:          2,
+		UserStatusSync: true,
+		Bucket:         UserBucket{Name: "bucket", Marker: "zone.1.2", BucketID: "zone.1.2", PlacementID: "default-placement"},
+	}, entry)
+}
+
+func TestDecodeErrorFieldPath(t *testing.T) {
+	testcases := []struct {
+		file    string
+		corrupt func([]byte)
+		target  error
+		expect  DecodeError
+	}{
+		{
+			file: "testdata/manifest_1",
+			corrupt: func(data []byte) {
+				copy(data[0x47:], []byte{0xff, 0xff, 0xff, 0xff})
+			},
+			target: ErrTruncated,
+			expect: DecodeError{
+				Type:    "rgw_bucket",
+				Field:   "RGWObjManifest.obj.bucket.marker",
+				Offset:  0x4b,
+				Version: 10,
+				Compat:  10,
+			},
+		},
+		{
+			file: "testdata/manifest_2",
+			corrupt: func(data []byte) {
+				copy(data[0x1a0:], []byte{0xff, 0xff, 0xff, 0xff})
+			},
+			target: ErrTruncated,
+			expect: DecodeError{
+				Type:    "RGWObjManifestRule",
+				Field:   "RGWObjManifest.rules[1].override_prefix",
+				Offset:  0x1a4,
+				Version: 2,
+				Compat:  1,
+			},
+		},
+		{
+			file: "testdata/manifest_3",
+			corrupt: func(data []byte) {
+				data[1] = 8
+			},
+			target: ErrOldVersion,
+			expect: DecodeError{
+				Type:    "RGWObjManifest",
+				Field:   "RGWObjManifest",
+				Offset:  2,
+				Version: 7,
+				Compat:  8,
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
+			tt.corrupt(data)
+
+			_, err = DecodeRGWObjManifest(data)
+			assert.True(t, errors.Is(err, tt.target), "%v", err)
+			var de *DecodeError
+			assert.True(t, errors.As(err, &de))
+			assert.Equal(t, tt.expect.Type, de.Type)
+			assert.Equal(t, tt.expect.Field, de.Field)
+			assert.Equal(t, tt.expect.Offset, de.Offset)
+			assert.Equal(t, tt.expect.Version, de.Version)
+			assert.Equal(t, tt.expect.Compat, de.Compat)
+		})
+	}
+}
+
+func TestDecodeUserBucketEntryIncompatible(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	// Turn the legacy empty bucket name into a one byte string.
+	data[6] = 1
+
+	_, err = DecodeUserBucketEntry(data)
+	assert.True(t, errors.Is(err, ErrIncompatible))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "cls_user_bucket_entry.empty_str", de.Field)
+}
+
+func TestDecoderCompose(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	manifest, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+
+	d := NewDecoder(data)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("RGWObjManifest", 7, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(7), structV)
+	d.Field("obj_size")
+	objSize, err := d.DecodeU64()
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.ObjSize, objSize)
+	d.Field("objs")
+	count, err := d.DecodeU32()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), count)
+	d.Field("explicit_objs")
+	_, err = d.DecodeBool()
+	assert.NoError(t, err)
+	d.Field("obj")
+	obj, err := d.DecodeRGWObj()
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Obj, *obj)
+
+	d.Offset = structEnd
+	assert.NoError(t, d.DecodeFinish(structEnd))
+	assert.Equal(t, uint32(0), d.Remaining())
+}
+
+func TestDecodePrimitivesDoNotAllocate(t *testing.T) {
+	e := NewEncoder()
+	e.EncodeU8(1)
+	e.EncodeU16(2)
+	e.EncodeU32(3)
+	e.EncodeU64(4)
+	e.EncodeString("")
+	data := e.Bytes()
+	d := NewDecoder(data)
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Offset = 0
+		_, _ = d.DecodeU8()
+		_, _ = d.DecodeU16()
+		_, _ = d.DecodeU32()
+		_, _ = d.DecodeU64()
+		_, _ = d.DecodeString()
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestStringTable(t *testing.T) {
+	e := NewEncoder()
+	e.EncodeString("marker")
+	e.EncodeString("marker")
+	e.EncodeString("other")
+	e.EncodeString("other")
+	strings := NewStringTable(1)
+	d := NewDecoder(e.Bytes(), DecodeOptions{Strings: strings})
+	var decoded []string
+	for d.Remaining() > 0 {
+		s, err := d.DecodeString()
+		assert.NoError(t, err)
+		decoded = append(decoded, s)
+	}
+	assert.Equal(t, []string{"marker", "marker", "other", "other"}, decoded)
+	assert.Equal(t, map[string]string{"marker": "marker"}, strings.strings)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Offset = 0
+		_, _ = d.DecodeString()
+	})
+	assert.Zero(t, allocs)
+}
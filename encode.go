@@ -0,0 +1,127 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Encoder produces Ceph encoded values. It mirrors Decoder: every DecodeX
+// method has an EncodeX counterpart writing the same wire format.
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder returns an empty Encoder.
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Bytes returns the encoded data.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Len returns the number of bytes encoded so far.
+func (e *Encoder) Len() int {
+	return len(e.buf)
+}
+
+// WriteBytes appends b as is.
+func (e *Encoder) WriteBytes(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) EncodeU8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *Encoder) EncodeBool(v bool) {
+	if v {
+		e.EncodeU8(1)
+	} else {
+		e.EncodeU8(0)
+	}
+}
+
+func (e *Encoder) EncodeU16(v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) EncodeU32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) EncodeU64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) EncodeString(s string) {
+	e.EncodeU32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *Encoder) EncodeTime(s, ns uint32) {
+	e.EncodeU32(s)
+	e.EncodeU32(ns)
+}
+
+func (e *Encoder) EncodeI32(v int32) {
+	e.EncodeU32(uint32(v))
+}
+
+func (e *Encoder) EncodeI64(v int64) {
+	e.EncodeU64(uint64(v))
+}
+
+func (e *Encoder) EncodeBufferlist(b []byte) {
+	e.EncodeU32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) EncodeRealTime(t time.Time) {
+	e.EncodeTime(timeParts(t))
+}
+
+func (e *Encoder) EncodeUTime(t time.Time) {
+	e.EncodeRealTime(t)
+}
+
+func (e *Encoder) EncodeUUID(u UUID) {
+	e.buf = append(e.buf, u[:]...)
+}
+
+// EncodeStart writes an ENCODE_START header for struct version v and compat
+// version compat. The returned position must be passed to EncodeFinish once
+// the struct body has been written.
+func (e *Encoder) EncodeStart(v, compat uint8) int {
+	e.EncodeU8(v)
+	e.EncodeU8(compat)
+	start := len(e.buf)
+	e.EncodeU32(0)
+	return start
+}
+
+// EncodeFinish patches the length of the struct started at start.
+func (e *Encoder) EncodeFinish(start int) {
+	binary.LittleEndian.PutUint32(e.buf[start:], uint32(len(e.buf)-start-4))
+}
+
+func timeFromParts(s, ns uint32) time.Time {
+	if s == 0 && ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(s), int64(ns)).UTC()
+}
+
+func timeParts(t time.Time) (s uint32, ns uint32) {
+	if t.IsZero() {
+		return 0, 0
+	}
+	return uint32(t.Unix()), uint32(t.Nanosecond())
+}
@@ -0,0 +1,133 @@
+package decoder
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTripUserBucketEntry(data []byte) ([]byte, error) {
+	u, err := DecodeUserBucketEntry(data)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeUserBucketEntry(u), nil
+}
+
+func roundTripRGWObjManifest(data []byte) ([]byte, error) {
+	m, err := DecodeRGWObjManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeRGWObjManifest(m), nil
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	testcases := []struct {
+		file      string
+		roundTrip func([]byte) ([]byte, error)
+	}{
+		{"testdata/bucket_entry_1", roundTripUserBucketEntry},
+		{"testdata/manifest_1", roundTripRGWObjManifest},
+		{"testdata/manifest_2", roundTripRGWObjManifest},
+		{"testdata/manifest_3", roundTripRGWObjManifest},
+		{"testdata/manifest_4", roundTripRGWObjManifest},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
+			encoded, err := tt.roundTrip(data)
+			assert.NoError(t, err)
+			assert.Equal(t, data, encoded)
+		})
+	}
+}
+
+func TestEncodeDecodeManifest(t *testing.T) {
+	bucket := RGWBucket{
+		Tenant:   "tenant",
+		Name:     "bucket",
+		Marker:   "zone.1234.1",
+		BucketID: "zone.1234.1",
+	}
+	tailBucket := bucket
+	tailBucket.ExplicitPlacement = RGWDataPlacementTarget{
+		DataPool:      RGWPool{Name: "data"},
+		DataExtraPool: RGWPool{Name: "extra", NS: "ns"},
+		IndexPool:     RGWPool{Name: "index"},
+	}
+	part := RGWObjManifestPart{
+		Loc: RGWObj{
+			Bucket: bucket,
+			Key:    RGWObjKey{Name: "obj", NS: "shadow", Instance: "v1"},
+		},
+		LocOfs: 1,
+		Size:   2,
+	}
+	expected := &RGWObjManifest{
+		ObjSize:     20 << 20,
+		HeadSize:    4 << 20,
+		MaxHeapSize: 4 << 20,
+		Prefix:      ".prefix_",
+		Objs: map[uint64]RGWObjManifestPart{
+			4 << 20: part,
+			8 << 20: part,
+		},
+		Rules: map[uint64]RGWObjManifestRule{
+			0: {StripeMaxSize: 4 << 20},
+		},
+		Obj: RGWObj{
+			Bucket: bucket,
+			Key:    RGWObjKey{Name: "obj", Instance: "v1"},
+		},
+		TailInstance: "v2",
+		TailPlacement: RGWBucketPlacement{
+			Bucket:        tailBucket,
+			PlacementRule: RGWPlacementRule{Name: "default-placement", StorageClass: "COLD"},
+		},
+		HeadPlacementRule: RGWPlacementRule{Name: "default-placement"},
+	}
+
+	data := EncodeRGWObjManifest(expected)
+	m, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Objs, m.Objs)
+	assert.Equal(t, expected.Rules, m.Rules)
+	assert.Equal(t, expected.Obj, m.Obj)
+	assert.Equal(t, expected.TailInstance, m.TailInstance)
+	assert.Equal(t, expected.TailPlacement, m.TailPlacement)
+	assert.Equal(t, expected.HeadPlacementRule, m.HeadPlacementRule)
+	assert.Equal(t, data, EncodeRGWObjManifest(m))
+}
+
+func TestEncodeDecodeUserBucket(t *testing.T) {
+	for _, expected := range []*UserBucket{
+		{
+			Name:        "bucket",
+			Marker:      "zone.1.2",
+			BucketID:    "zone.1.2",
+			PlacementID: "default-placement",
+		},
+		{
+			Name:     "bucket",
+			Marker:   "zone.1.2",
+			BucketID: "zone.1.2",
+			ExplicitPlacement: explicitPlacement{
+				DataPool:      "data",
+				IndexPool:     "index",
+				DataExtraPool: "extra",
+			},
+		},
+	} {
+		u, err := DecodeUserBucket(EncodeUserBucket(expected), DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, u)
+
+		entry := &UserBucketEntry{Size: 10, SizeRounded: 4096, Count: 1, Bucket: *expected}
+		decoded, err := DecodeUserBucketEntry(EncodeUserBucketEntry(entry), DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+		assert.NoError(t, err)
+		assert.Equal(t, entry, decoded)
+	}
+}
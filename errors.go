@@ -0,0 +1,190 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors describing why a decode failed. Every error returned by the
+// decoders wraps one of them, so they can be tested with errors.Is.
+var (
+	ErrOldVersion   = errors.New("DECODE_ERR_OLDVERSION")
+	ErrPastEnd      = errors.New("DECODE_ERR_PAST")
+	ErrTruncated    = errors.New("DECODE_ERR_TRUNCATED")
+	ErrIncompatible = errors.New("DECODE_ERR_INCOMPATIBLE")
+	ErrNewVersion   = errors.New("DECODE_ERR_NEWVERSION")
+	ErrLeftover     = errors.New("DECODE_ERR_LEFTOVER")
+)
+
+// ErrUnknownType is returned by Decode for type names that are not
+// registered.
+var ErrUnknownType = errors.New("unknown type")
+
+// ErrBucketMismatch is returned by RGWBucketEntryPoint.CheckUserBucket for a
+// bucket that is not the one the entrypoint links to.
+var ErrBucketMismatch = errors.New("bucket does not match its entrypoint")
+
+// ErrStatsMismatch is returned by UserBucketEntry.CheckStats for stats that
+// differ from those of the bucket index.
+var ErrStatsMismatch = errors.New("bucket stats do not match the bucket index")
+
+// TruncatedError is returned when the input ends before a field could be
+// read completely.
+type TruncatedError struct {
+	Offset    uint32
+	Need      uint32
+	Remaining uint32
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("DECODE_ERR_TRUNCATED: need %d bytes at offset %d, %d remaining",
+		e.Need, e.Offset, e.Remaining)
+}
+
+func (e *TruncatedError) Is(target error) bool {
+	return target == ErrTruncated
+}
+
+// RecordError is returned by Reader for a record that could not be read.
+// Offset is the position of the record in the stream, offsets in Err are
+// relative to the record.
+type RecordError struct {
+	Offset uint64
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError describes where in the input a decode failed. Type, Version
+// and Compat refer to the innermost struct being decoded, Field is the path
+// of the field from the top-level type, e.g.
+// "RGWObjManifest.objs[3].loc.bucket.marker".
+type DecodeError struct {
+	Type    string
+	Field   string
+	Offset  uint32
+	Version uint8
+	Compat  uint8
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("decode")
+	if e.Field != "" {
+		b.WriteString(" ")
+		b.WriteString(e.Field)
+	}
+	b.WriteString(" at offset ")
+	b.WriteString(strconv.FormatUint(uint64(e.Offset), 10))
+	if e.Type != "" {
+		fmt.Fprintf(&b, " (%s struct_v %d, compat %d)", e.Type, e.Version, e.Compat)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type frame struct {
+	typ     string
+	field   string
+	index   uint32
+	indexed bool
+	v       uint8
+	compat  uint8
+}
+
+func (d *Decoder) pushFrame(typ string) {
+	d.frames = append(d.frames, frame{
+		typ: typ,
+	})
+}
+
+func (d *Decoder) popFrame() {
+	if len(d.frames) > 0 {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
+
+func (d *Decoder) setFrameVersion(v, compat uint8) {
+	if len(d.frames) > 0 {
+		f := &d.frames[len(d.frames)-1]
+		f.v = v
+		f.compat = compat
+	}
+}
+
+// Field names the field of the current struct that is about to be decoded.
+// The name shows up in the field path of a DecodeError.
+func (d *Decoder) Field(name string) {
+	if len(d.frames) > 0 {
+		f := &d.frames[len(d.frames)-1]
+		f.field = name
+		f.indexed = false
+	}
+}
+
+// FieldIndex names the i-th element of a container field of the current
+// struct.
+func (d *Decoder) FieldIndex(name string, i uint32) {
+	if len(d.frames) > 0 {
+		f := &d.frames[len(d.frames)-1]
+		f.field = name
+		f.index = i
+		f.indexed = true
+	}
+}
+
+func (d *Decoder) fieldPath() string {
+	if len(d.frames) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(d.frames[0].typ)
+	for _, f := range d.frames {
+		if f.field == "" {
+			continue
+		}
+		b.WriteByte('.')
+		b.WriteString(f.field)
+		if f.indexed {
+			b.WriteByte('[')
+			b.WriteString(strconv.FormatUint(uint64(f.index), 10))
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// NewError wraps err into a DecodeError describing the current position of
+// the decoder. Errors that already are a DecodeError are returned as is.
+func (d *Decoder) NewError(err error) error {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return err
+	}
+	e := &DecodeError{
+		Field:  d.fieldPath(),
+		Offset: d.Offset,
+		Err:    err,
+	}
+	if len(d.frames) > 0 {
+		f := d.frames[len(d.frames)-1]
+		e.Type = f.typ
+		e.Version = f.v
+		e.Compat = f.compat
+	}
+	return e
+}
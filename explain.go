@@ -0,0 +1,180 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Explanation records where every field of a decode was found in the input.
+// Set DecodeOptions.Explain to collect one.
+type Explanation struct {
+	Spans []Span
+}
+
+// Span is a run of input bytes read as one field. Struct headers carry
+// Header, unknown trailing struct bytes and bytes following the top-level
+// value have Unknown set and no value.
+type Span struct {
+	Field   string
+	Offset  uint32
+	Raw     []byte
+	Value   interface{}
+	Header  *StructHeader
+	Unknown bool
+}
+
+// StructHeader is a decoded DECODE_START header. HasLen is false for legacy
+// encodings without a length.
+type StructHeader struct {
+	Type    string
+	Version uint8
+	Compat  uint8
+	Len     uint32
+	HasLen  bool
+}
+
+func (h *StructHeader) String() string {
+	s := fmt.Sprintf("%s struct_v %d, compat %d", h.Type, h.Version, h.Compat)
+	if h.HasLen {
+		s += fmt.Sprintf(", len %d", h.Len)
+	}
+	return s
+}
+
+func (s Span) describe() string {
+	switch {
+	case s.Header != nil:
+		return s.Field + ": " + s.Header.String()
+	case s.Unknown:
+		return s.Field + ": unknown bytes"
+	case s.Value == nil:
+		return s.Field
+	}
+	if str, ok := s.Value.(string); ok {
+		return fmt.Sprintf("%s = %q", s.Field, str)
+	}
+	return fmt.Sprintf("%s = %v", s.Field, s.Value)
+}
+
+const hexdumpWidth = 16
+
+// WriteHexdump writes the input as a hexdump with every span on lines of its
+// own, annotated with the field it was decoded as:
+//
+//	00000000  07 06 8b 01 00 00        RGWObjManifest: RGWObjManifest struct_v 7, compat 6, len 395
+//	00000006  5b 9e 06 00 00 00 00 00  RGWObjManifest.obj_size = 433755
+//
+// with the hex column padded to 16 bytes.
+func (e *Explanation) WriteHexdump(w io.Writer) error {
+	for _, s := range e.Spans {
+		for i := 0; i == 0 || i < len(s.Raw); i += hexdumpWidth {
+			end := i + hexdumpWidth
+			if end > len(s.Raw) {
+				end = len(s.Raw)
+			}
+			var b strings.Builder
+			for j, c := range s.Raw[i:end] {
+				if j > 0 {
+					b.WriteByte(' ')
+				}
+				fmt.Fprintf(&b, "%02x", c)
+			}
+			var err error
+			if i == 0 {
+				_, err = fmt.Fprintf(w, "%08x  %-*s  %s\n", s.Offset, hexdumpWidth*3-1, b.String(), s.describe())
+			} else {
+				_, err = fmt.Fprintf(w, "%08x  %s\n", s.Offset+uint32(i), b.String())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Explanation) String() string {
+	var b strings.Builder
+	_ = e.WriteHexdump(&b)
+	return b.String()
+}
+
+func (e *Explanation) MarshalJSON() ([]byte, error) {
+	spans := e.Spans
+	if spans == nil {
+		spans = []Span{}
+	}
+	return json.Marshal(spans)
+}
+
+func (s Span) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string        `json:"field"`
+		Offset  uint32        `json:"offset"`
+		Length  int           `json:"length"`
+		Raw     string        `json:"raw"`
+		Value   interface{}   `json:"value,omitempty"`
+		Header  *StructHeader `json:"header,omitempty"`
+		Unknown bool          `json:"unknown,omitempty"`
+	}{
+		Field:   s.Field,
+		Offset:  s.Offset,
+		Length:  len(s.Raw),
+		Raw:     hex.EncodeToString(s.Raw),
+		Value:   s.Value,
+		Header:  s.Header,
+		Unknown: s.Unknown,
+	})
+}
+
+func (h *StructHeader) MarshalJSON() ([]byte, error) {
+	var structLen *uint32
+	if h.HasLen {
+		structLen = &h.Len
+	}
+	return json.Marshal(struct {
+		Type    string  `json:"type"`
+		Version uint8   `json:"struct_v"`
+		Compat  uint8   `json:"compat"`
+		Len     *uint32 `json:"len,omitempty"`
+	}{
+		Type:    h.Type,
+		Version: h.Version,
+		Compat:  h.Compat,
+		Len:     structLen,
+	})
+}
+
+// beginSpan starts a span at the current offset. Fields read while a span
+// is open belong to it, so only the outermost primitive is recorded.
+func (d *Decoder) beginSpan() uint32 {
+	d.spanDepth++
+	return d.Offset
+}
+
+// endSpan closes the span started at start, recording it if it is the
+// outermost one and was read completely.
+func (d *Decoder) endSpan(start uint32, value interface{}, header *StructHeader, err error) {
+	d.spanDepth--
+	if d.spanDepth > 0 || err != nil {
+		return
+	}
+	d.addSpan(Span{
+		Offset: start,
+		Raw:    d.Data[start:d.Offset],
+		Value:  value,
+		Header: header,
+	})
+}
+
+func (d *Decoder) addSpan(s Span) {
+	s.Field = d.fieldPath()
+	d.opts.Explain.Spans = append(d.opts.Explain.Spans, s)
+}
+
+func (d *Decoder) explaining() bool {
+	return d.opts.Explain != nil
+}
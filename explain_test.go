@@ -0,0 +1,97 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+
+	var e Explanation
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{Explain: &e})
+	assert.NoError(t, err)
+
+	// The spans cover the input without gaps or overlaps.
+	var offset uint32
+	for _, s := range e.Spans {
+		assert.Equal(t, offset, s.Offset, s.Field)
+		assert.Equal(t, data[s.Offset:s.Offset+uint32(len(s.Raw))], s.Raw)
+		offset += uint32(len(s.Raw))
+	}
+	assert.Equal(t, uint32(len(data)), offset)
+
+	assert.Equal(t, Span{
+		Field:  "RGWObjManifest",
+		Offset: 0,
+		Raw:    data[:6],
+		Header: &StructHeader{Type: "RGWObjManifest", Version: 7, Compat: 6, Len: 395, HasLen: true},
+	}, e.Spans[0])
+	assert.Equal(t, Span{
+		Field:  "RGWObjManifest.obj_size",
+		Offset: 6,
+		Raw:    data[6:14],
+		Value:  uint64(433755),
+	}, e.Spans[1])
+
+	lines := strings.Split(e.String(), "\n")
+	assert.Equal(t, "00000000  07 06 8b 01 00 00                                RGWObjManifest: RGWObjManifest struct_v 7, compat 6, len 395", lines[0])
+	assert.Equal(t, "00000006  5b 9e 06 00 00 00 00 00                          RGWObjManifest.obj_size = 433755", lines[1])
+	assert.Contains(t, lines, `0000001f  24 00 00 00 67 75 61 7a 69 2d 73 61 6c 65 2d 64  RGWObjManifest.obj.bucket.name = "guazi-sale-delivery-agent-image-test"`)
+	assert.Contains(t, lines, "0000002f  65 6c 69 76 65 72 79 2d 61 67 65 6e 74 2d 69 6d")
+
+	b, err := json.Marshal(&e)
+	assert.NoError(t, err)
+	var spans []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &spans))
+	assert.Equal(t, len(e.Spans), len(spans))
+	assert.Equal(t, map[string]interface{}{
+		"field":  "RGWObjManifest",
+		"offset": 0.0,
+		"length": 6.0,
+		"raw":    "07068b010000",
+		"header": map[string]interface{}{"type": "RGWObjManifest", "struct_v": 7.0, "compat": 6.0, "len": 395.0},
+	}, spans[0])
+}
+
+func TestExplainDecodeError(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	copy(data[0x47:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	var e Explanation
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{Explain: &e})
+	assert.Error(t, err)
+	last := e.Spans[len(e.Spans)-1]
+	assert.Equal(t, "RGWObjManifest.obj.bucket.name", last.Field)
+	assert.Equal(t, uint32(0x47), last.Offset+uint32(len(last.Raw)))
+}
+
+func TestExplainUnknownBytes(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	data[2]++
+	data = append(data, 0xaa, 0xbb)
+
+	var e Explanation
+	_, err = DecodeUserBucketEntry(data, DecodeOptions{Explain: &e})
+	assert.NoError(t, err)
+	n := len(e.Spans)
+	assert.Equal(t, Span{
+		Field:   "cls_user_bucket_entry",
+		Offset:  uint32(len(data) - 2),
+		Raw:     []byte{0xaa},
+		Unknown: true,
+	}, e.Spans[n-2])
+	assert.Equal(t, Span{
+		Offset:  uint32(len(data) - 1),
+		Raw:     []byte{0xbb},
+		Unknown: true,
+	}, e.Spans[n-1])
+}
@@ -0,0 +1,288 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	fuzzIters = flag.Int("fuzz.iters", 2000, "mutations to try per seed file")
+	fuzzSeed  = flag.Int64("fuzz.seed", 1, "random seed of the fuzzer, 0 picks one from the clock")
+)
+
+const (
+	crashersDir = "testdata/crashers"
+	// A decode may not allocate more than fuzzAllocFactor times the size of
+	// its input, plus fuzzAllocSlack bytes of fixed overhead.
+	fuzzAllocFactor = 256
+	fuzzAllocSlack  = 64 << 10
+	fuzzTimeout     = time.Second
+)
+
+// fuzzTargets are the entry points exercised by the fuzzer. Manifests are
+// also JSON marshaled, which walks the decoded rules. RadosObjectsKeys is
+// left out: it lists one key per stripe, so its output legitimately grows
+// with obj_size rather than with the input.
+var fuzzTargets = []struct {
+	name   string
+	decode func([]byte)
+}{
+	{"DecodeRGWObjManifest", func(data []byte) {
+		m, err := DecodeRGWObjManifest(data)
+		if err == nil {
+			_, _ = m.MarshalJSON()
+		}
+	}},
+	{"DecodeUserBucketEntry", func(data []byte) {
+		_, _ = DecodeUserBucketEntry(data)
+	}},
+	{"DecodeUserBucket", func(data []byte) {
+		_, _ = DecodeUserBucket(data)
+	}},
+	{"DecodeAccessKey", func(data []byte) {
+		_, _ = DecodeAccessKey(data)
+	}},
+	{"DecodeRGWUID", func(data []byte) {
+		_, _ = DecodeRGWUID(data)
+	}},
+	{"DecodeRGWBucketDirEntry", func(data []byte) {
+		_, _ = DecodeRGWBucketDirEntry(data)
+	}},
+	{"DecodeRGWBucketOLHEntry", func(data []byte) {
+		_, _ = DecodeRGWBucketOLHEntry(data)
+	}},
+	{"DecodeRGWBILogEntry", func(data []byte) {
+		_, _ = DecodeRGWBILogEntry(data)
+	}},
+	{"DecodeRGWCompressionInfo", func(data []byte) {
+		_, _ = DecodeRGWCompressionInfo(data)
+	}},
+	{"DecodeRGWAccessControlPolicy", func(data []byte) {
+		_, _ = DecodeRGWAccessControlPolicy(data)
+	}},
+	{"DecodeRGWBucketInfo", func(data []byte) {
+		_, _ = DecodeRGWBucketInfo(data)
+	}},
+	{"DecodeRGWBucketEntryPoint", func(data []byte) {
+		_, _ = DecodeRGWBucketEntryPoint(data)
+	}},
+	{"DecodeRGWUserInfo", func(data []byte) {
+		_, _ = DecodeRGWUserInfo(data)
+	}},
+	{"DecodeRGWBucketDirHeader", func(data []byte) {
+		_, _ = DecodeRGWBucketDirHeader(data)
+	}},
+	{"DecodeRGWObjTags", func(data []byte) {
+		_, _ = DecodeRGWObjTags(data)
+	}},
+	{"DecodeRGWOLHInfo", func(data []byte) {
+		_, _ = DecodeRGWOLHInfo(data)
+	}},
+	{"DecodeObjectAttrs", func(data []byte) {
+		for _, name := range objectAttrsFuzzNames {
+			_, _ = DecodeObjectAttrs(map[string][]byte{name: data})
+		}
+	}},
+}
+
+// objectAttrsFuzzNames are the encoded attrs DecodeObjectAttrs is fuzzed
+// with, one at a time so that an error in one does not hide the others.
+var objectAttrsFuzzNames = []string{
+	RGWAttrManifest,
+	RGWAttrACL,
+	RGWAttrPGVer,
+	RGWAttrSourceZone,
+	RGWAttrTags,
+	RGWAttrCompression,
+	RGWAttrOLHInfo,
+}
+
+// fuzzSeeds are encoded sample values for the targets testdata has no blob
+// for, so that their mutations get past the struct header.
+var fuzzSeeds = map[string][]byte{
+	"DecodeRGWBucketInfo": EncodeRGWBucketInfo(testBucketInfo()),
+	"DecodeRGWBucketEntryPoint": EncodeRGWBucketEntryPoint(&RGWBucketEntryPoint{
+		Bucket:       RGWBucket{Tenant: "tenant", Name: "bucket", Marker: "zone.1234.1", BucketID: "zone.1234.2"},
+		Owner:        RGWUser{Tenant: "tenant", ID: "user"},
+		CreationTime: time.Unix(1600000000, 5000),
+		Linked:       true,
+	}),
+	"DecodeRGWUserInfo": EncodeRGWUserInfo(testUserInfo()),
+	"DecodeRGWBucketDirHeader": EncodeRGWBucketDirHeader(&RGWBucketDirHeader{
+		Stats: map[RGWObjCategory]RGWBucketCategoryStats{
+			RGWObjCategoryMain:      {TotalSize: 10000, TotalSizeRounded: 12288, NumEntries: 3, ActualSize: 10000},
+			RGWObjCategoryMultiMeta: {NumEntries: 1},
+		},
+		TagTimeout:  120,
+		Ver:         17,
+		MasterVer:   2,
+		MaxMarker:   "00000000017.17.5",
+		NewInstance: ClsRGWBucketInstanceEntry{ReshardStatus: ReshardInProgress, NewBucketInstanceID: "zone.4217.9", NumShards: 23},
+		SyncStopped: true,
+	}),
+	"DecodeRGWObjTags": EncodeRGWObjTags(&RGWObjTags{Tags: map[string]string{"project": "x", "team": "storage"}}),
+	"DecodeRGWOLHInfo": EncodeRGWOLHInfo(&RGWOLHInfo{Target: RGWObj{
+		Bucket: RGWBucket{Name: "bucket", Marker: "zone.4217.1", BucketID: "zone.4217.1"},
+		Key:    RGWObjKey{Name: "cat.jpg", Instance: "XgfYa"},
+	}}),
+	"DecodeObjectAttrs": EncodeRGWCompressionInfo(&RGWCompressionInfo{
+		CompressionType: "zstd",
+		OrigSize:        8 << 20,
+		Blocks:          []CompressionBlock{{Len: 1000}, {OldOfs: 4 << 20, NewOfs: 1000, Len: 2000}},
+	}),
+}
+
+// checkDecode runs decode on data and returns a description of the first
+// violated invariant: the decode must not panic, must finish within
+// fuzzTimeout and must stay within the allocation budget.
+func checkDecode(decode func([]byte), data []byte) error {
+	done := make(chan error, 1)
+	go func() {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		decode(data)
+		runtime.ReadMemStats(&after)
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(len(data))*fuzzAllocFactor+fuzzAllocSlack {
+			done <- fmt.Errorf("allocated %d bytes for %d bytes of input", alloc, len(data))
+			return
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(fuzzTimeout):
+		return fmt.Errorf("no result after %v", fuzzTimeout)
+	}
+}
+
+var interesting = [][]byte{
+	{0x00},
+	{0xff},
+	{0x00, 0x00, 0x00, 0x00},
+	{0xff, 0xff, 0xff, 0xff},
+	{0xff, 0xff, 0xff, 0x7f},
+	{0x00, 0x00, 0x00, 0x80},
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+}
+
+// mutate returns a copy of data with a few random edits applied.
+func mutate(r *rand.Rand, data []byte) []byte {
+	out := append([]byte{}, data...)
+	for n := 1 + r.Intn(4); n > 0; n-- {
+		if len(out) == 0 {
+			out = append(out, byte(r.Intn(256)))
+			continue
+		}
+		pos := r.Intn(len(out))
+		switch r.Intn(6) {
+		case 0:
+			out[pos] ^= 1 << uint(r.Intn(8))
+		case 1:
+			out[pos] = byte(r.Intn(256))
+		case 2:
+			copy(out[pos:], interesting[r.Intn(len(interesting))])
+		case 3:
+			out = out[:pos]
+		case 4:
+			end := pos + 1 + r.Intn(8)
+			if end > len(out) {
+				end = len(out)
+			}
+			out = append(out[:pos], out[end:]...)
+		case 5:
+			ins := make([]byte, 1+r.Intn(8))
+			r.Read(ins)
+			out = append(out[:pos], append(ins, out[pos:]...)...)
+		}
+	}
+	return out
+}
+
+func saveCrasher(t *testing.T, data []byte) string {
+	sum := sha1.Sum(data)
+	name := filepath.Join(crashersDir, hex.EncodeToString(sum[:]))
+	if err := os.MkdirAll(crashersDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// TestFuzz mutates the testdata blobs and feeds them to every decoder, and
+// the fuzzSeeds to the decoder they were encoded for. Inputs breaking an
+// invariant are written to testdata/crashers, where TestCrashers keeps
+// checking them. Use -fuzz.iters and -fuzz.seed for longer or different
+// runs.
+func TestFuzz(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping fuzzing in short mode")
+	}
+	seed := *fuzzSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Logf("fuzz seed %d", seed)
+	r := rand.New(rand.NewSource(seed))
+
+	seeds, err := filepath.Glob("testdata/*_[0-9]")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, seeds)
+	for _, file := range seeds {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		for i := 0; i < *fuzzIters; i++ {
+			input := mutate(r, data)
+			for _, target := range fuzzTargets {
+				if err := checkDecode(target.decode, input); err != nil {
+					t.Errorf("%s: %v, input saved to %s", target.name, err, saveCrasher(t, input))
+				}
+			}
+		}
+	}
+	for _, target := range fuzzTargets {
+		data, ok := fuzzSeeds[target.name]
+		if !ok {
+			continue
+		}
+		for i := 0; i < *fuzzIters; i++ {
+			input := mutate(r, data)
+			if err := checkDecode(target.decode, input); err != nil {
+				t.Errorf("%s: %v, input saved to %s", target.name, err, saveCrasher(t, input))
+			}
+		}
+	}
+}
+
+// TestCrashers replays the inputs found by TestFuzz.
+func TestCrashers(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(crashersDir, "*"))
+	assert.NoError(t, err)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		for _, target := range fuzzTargets {
+			assert.NoError(t, checkDecode(target.decode, data), "%s(%s)", target.name, file)
+		}
+	}
+}
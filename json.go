@@ -0,0 +1,1026 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The MarshalJSON methods below reproduce the dump() output of the Ceph
+// types, field for field and in the same order, so that the JSON can be
+// compared with `ceph-dencoder ... dump_json` and radosgw-admin.
+
+type jsonKeyVal struct {
+	Key interface{} `json:"key"`
+	Val interface{} `json:"val"`
+}
+
+func (r RGWObjManifest) MarshalJSON() ([]byte, error) {
+	objs := make([]interface{}, 0, 2*len(r.Objs))
+	for _, k := range sortedPartKeys(r.Objs) {
+		objs = append(objs, k, r.Objs[k])
+	}
+	rules := make([]jsonKeyVal, 0, len(r.Rules))
+	for _, k := range r.Rules.keys() {
+		rules = append(rules, jsonKeyVal{Key: k, Val: r.Rules[k]})
+	}
+	return json.Marshal(struct {
+		Objs          []interface{}      `json:"objs"`
+		ObjSize       uint64             `json:"obj_size"`
+		ExplicitObjs  bool               `json:"explicit_objs"`
+		HeadSize      uint64             `json:"head_size"`
+		MaxHeadSize   uint64             `json:"max_head_size"`
+		Prefix        string             `json:"prefix"`
+		Rules         []jsonKeyVal       `json:"rules"`
+		TailInstance  string             `json:"tail_instance"`
+		TailPlacement RGWBucketPlacement `json:"tail_placement"`
+		BeginIter     ObjIterator        `json:"begin_iter"`
+		EndIter       ObjIterator        `json:"end_iter"`
+	}{
+		Objs:          objs,
+		ObjSize:       r.ObjSize,
+		ExplicitObjs:  r.ExplicitObjs,
+		HeadSize:      r.HeadSize,
+		MaxHeadSize:   r.MaxHeapSize,
+		Prefix:        r.Prefix,
+		Rules:         rules,
+		TailInstance:  r.TailInstance,
+		TailPlacement: r.TailPlacement,
+		BeginIter:     r.BeginIter,
+		EndIter:       r.EndIter,
+	})
+}
+
+func (r RGWObjManifestPart) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Loc    RGWObj `json:"loc"`
+		LocOfs uint64 `json:"loc_ofs"`
+		Size   uint64 `json:"size"`
+	}{
+		Loc:    r.Loc,
+		LocOfs: r.LocOfs,
+		Size:   r.Size,
+	})
+}
+
+func (r RGWObjManifestRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		StartPartNum   uint32 `json:"start_part_num"`
+		StartOfs       uint64 `json:"start_ofs"`
+		PartSize       uint64 `json:"part_size"`
+		StripeMaxSize  uint64 `json:"stripe_max_size"`
+		OverridePrefix string `json:"override_prefix"`
+	}{
+		StartPartNum:   r.StartPartNum,
+		StartOfs:       r.StartOfs,
+		PartSize:       r.PartSize,
+		StripeMaxSize:  r.StripeMaxSize,
+		OverridePrefix: r.OverridePrefix,
+	})
+}
+
+func (o ObjIterator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		PartOfs           uint64       `json:"part_ofs"`
+		StripeOfs         uint64       `json:"stripe_ofs"`
+		Ofs               uint64       `json:"ofs"`
+		StripeSize        uint64       `json:"stripe_size"`
+		CurPartID         int32        `json:"cur_part_id"`
+		CurStripe         int32        `json:"cur_stripe"`
+		CurOverridePrefix string       `json:"cur_override_prefix"`
+		Location          RGWObjSelect `json:"location"`
+	}{
+		PartOfs:           o.PartOfs,
+		StripeOfs:         o.StripeOfs,
+		Ofs:               o.Ofs,
+		StripeSize:        o.StripeSize,
+		CurPartID:         o.CurPartID,
+		CurStripe:         o.CurStripe,
+		CurOverridePrefix: o.CurOverridePrefix,
+		Location:          o.Location,
+	})
+}
+
+func (r RGWObjSelect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		PlacementRule RGWPlacementRule `json:"placement_rule"`
+		Obj           RGWObj           `json:"obj"`
+		RawObj        RGWRawObj        `json:"raw_obj"`
+		IsRaw         bool             `json:"is_raw"`
+	}{
+		PlacementRule: r.PlacementRule,
+		Obj:           r.Obj,
+		RawObj:        r.RawObj,
+		IsRaw:         r.IsRaw,
+	})
+}
+
+func (r RGWBucketPlacement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Bucket        RGWBucket        `json:"bucket"`
+		PlacementRule RGWPlacementRule `json:"placement_rule"`
+	}{
+		Bucket:        r.Bucket,
+		PlacementRule: r.PlacementRule,
+	})
+}
+
+func (r RGWPlacementRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r RGWRawObj) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Pool RGWPool `json:"pool"`
+		Oid  string  `json:"oid"`
+		Loc  string  `json:"loc"`
+	}{
+		Pool: r.Pool,
+		Oid:  r.Oid,
+		Loc:  r.Loc,
+	})
+}
+
+func (r RGWObj) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Bucket RGWBucket `json:"bucket"`
+		Key    RGWObjKey `json:"key"`
+	}{
+		Bucket: r.Bucket,
+		Key:    r.Key,
+	})
+}
+
+func (r RGWBucket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name              string                 `json:"name"`
+		Marker            string                 `json:"marker"`
+		BucketID          string                 `json:"bucket_id"`
+		Tenant            string                 `json:"tenant"`
+		ExplicitPlacement RGWDataPlacementTarget `json:"explicit_placement"`
+	}{
+		Name:              r.Name,
+		Marker:            r.Marker,
+		BucketID:          r.BucketID,
+		Tenant:            r.Tenant,
+		ExplicitPlacement: r.ExplicitPlacement,
+	})
+}
+
+func (r RGWDataPlacementTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		DataPool      RGWPool `json:"data_pool"`
+		DataExtraPool RGWPool `json:"data_extra_pool"`
+		IndexPool     RGWPool `json:"index_pool"`
+	}{
+		DataPool:      r.DataPool,
+		DataExtraPool: r.DataExtraPool,
+		IndexPool:     r.IndexPool,
+	})
+}
+
+func (r RGWPool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r RGWObjKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name     string `json:"name"`
+		Instance string `json:"instance"`
+		NS       string `json:"ns"`
+	}{
+		Name:     r.Name,
+		Instance: r.Instance,
+		NS:       r.NS,
+	})
+}
+
+func (u UserBucket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name     string `json:"name"`
+		Marker   string `json:"marker"`
+		BucketID string `json:"bucket_id"`
+	}{
+		Name:     u.Name,
+		Marker:   u.Marker,
+		BucketID: u.BucketID,
+	})
+}
+
+func (u UserBucketEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Bucket         UserBucket `json:"bucket"`
+		Size           uint64     `json:"size"`
+		SizeRounded    uint64     `json:"size_rounded"`
+		CreationTime   utime      `json:"creation_time"`
+		Count          uint64     `json:"count"`
+		UserStatusSync bool       `json:"user_stats_sync"`
+	}{
+		Bucket:         u.Bucket,
+		Size:           u.Size,
+		SizeRounded:    u.SizeRounded,
+		CreationTime:   utime(u.CreationTime),
+		Count:          u.Count,
+		UserStatusSync: u.UserStatusSync,
+	})
+}
+
+func (r RGWBucketInfo) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(r.MDSearchConfig))
+	for k := range r.MDSearchConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	mdsearch := make([]jsonKeyVal, 0, len(keys))
+	for _, k := range keys {
+		mdsearch = append(mdsearch, jsonKeyVal{Key: k, Val: r.MDSearchConfig[k]})
+	}
+	normal := r.Layout.CurrentIndex.Layout.Normal
+	return json.Marshal(struct {
+		Bucket              RGWBucket        `json:"bucket"`
+		CreationTime        utime            `json:"creation_time"`
+		Owner               RGWUser          `json:"owner"`
+		Flags               uint32           `json:"flags"`
+		Zonegroup           string           `json:"zonegroup"`
+		PlacementRule       RGWPlacementRule `json:"placement_rule"`
+		HasInstanceObj      bool             `json:"has_instance_obj"`
+		Quota               RGWQuotaInfo     `json:"quota"`
+		NumShards           uint32           `json:"num_shards"`
+		BIShardHashType     uint8            `json:"bi_shard_hash_type"`
+		RequesterPays       bool             `json:"requester_pays"`
+		HasWebsite          bool             `json:"has_website"`
+		SwiftVersioning     bool             `json:"swift_versioning"`
+		SwiftVerLocation    string           `json:"swift_ver_location"`
+		IndexType           uint8            `json:"index_type"`
+		MDSearchConfig      []jsonKeyVal     `json:"mdsearch_config"`
+		ReshardStatus       uint8            `json:"reshard_status"`
+		NewBucketInstanceID string           `json:"new_bucket_instance_id"`
+		Layout              BucketLayout     `json:"layout"`
+	}{
+		Bucket:              r.Bucket,
+		CreationTime:        utime(r.CreationTime),
+		Owner:               r.Owner,
+		Flags:               r.Flags,
+		Zonegroup:           r.Zonegroup,
+		PlacementRule:       r.PlacementRule,
+		HasInstanceObj:      r.HasInstanceObj,
+		Quota:               r.Quota,
+		NumShards:           normal.NumShards,
+		BIShardHashType:     uint8(normal.HashType),
+		RequesterPays:       r.RequesterPays,
+		HasWebsite:          r.HasWebsite,
+		SwiftVersioning:     r.SwiftVersioning,
+		SwiftVerLocation:    r.SwiftVerLocation,
+		IndexType:           uint8(r.Layout.CurrentIndex.Layout.Type),
+		MDSearchConfig:      mdsearch,
+		ReshardStatus:       uint8(r.ReshardStatus),
+		NewBucketInstanceID: r.NewBucketInstanceID,
+		Layout:              r.Layout,
+	})
+}
+
+func (r RGWBucketEntryPoint) MarshalJSON() ([]byte, error) {
+	if r.HasBucketInfo {
+		return json.Marshal(struct {
+			OldBucketInfo *RGWBucketInfo `json:"old_bucket_info"`
+		}{
+			OldBucketInfo: r.OldBucketInfo,
+		})
+	}
+	return json.Marshal(struct {
+		Bucket        RGWBucket `json:"bucket"`
+		Owner         RGWUser   `json:"owner"`
+		CreationTime  utime     `json:"creation_time"`
+		Linked        bool      `json:"linked"`
+		HasBucketInfo bool      `json:"has_bucket_info"`
+	}{
+		Bucket:        r.Bucket,
+		Owner:         r.Owner,
+		CreationTime:  utime(r.CreationTime),
+		Linked:        r.Linked,
+		HasBucketInfo: r.HasBucketInfo,
+	})
+}
+
+func (r RGWUser) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r RGWUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UserID RGWUser `json:"user_id"`
+	}{
+		UserID: r.UserID,
+	})
+}
+
+func (r UserObject) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UID  RGWUID      `json:"uid"`
+		Info RGWUserInfo `json:"info"`
+	}{
+		UID:  r.UID,
+		Info: r.Info,
+	})
+}
+
+func (r ObjVersion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Ver uint64 `json:"ver"`
+		Tag string `json:"tag"`
+	}{
+		Ver: r.Ver,
+		Tag: r.Tag,
+	})
+}
+
+func (r RGWQuotaInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Enabled    bool  `json:"enabled"`
+		CheckOnRaw bool  `json:"check_on_raw"`
+		MaxSize    int64 `json:"max_size"`
+		MaxSizeKB  int64 `json:"max_size_kb"`
+		MaxObjects int64 `json:"max_objects"`
+	}{
+		Enabled:    r.Enabled,
+		CheckOnRaw: r.CheckOnRaw,
+		MaxSize:    r.MaxSize,
+		MaxSizeKB:  (r.MaxSize + 1023) / 1024,
+		MaxObjects: r.MaxObjects,
+	})
+}
+
+func (l BucketLayout) MarshalJSON() ([]byte, error) {
+	logs := l.Logs
+	if logs == nil {
+		logs = []BucketLogLayoutGeneration{}
+	}
+	return json.Marshal(struct {
+		Resharding   string                       `json:"resharding"`
+		CurrentIndex BucketIndexLayoutGeneration  `json:"current_index"`
+		TargetIndex  *BucketIndexLayoutGeneration `json:"target_index,omitempty"`
+		Logs         []BucketLogLayoutGeneration  `json:"logs"`
+	}{
+		Resharding:   l.Resharding.String(),
+		CurrentIndex: l.CurrentIndex,
+		TargetIndex:  l.TargetIndex,
+		Logs:         logs,
+	})
+}
+
+func (l BucketIndexLayoutGeneration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Gen    uint64            `json:"gen"`
+		Layout BucketIndexLayout `json:"layout"`
+	}{
+		Gen:    l.Gen,
+		Layout: l.Layout,
+	})
+}
+
+func (l BucketIndexLayout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type   string                  `json:"type"`
+		Normal BucketIndexNormalLayout `json:"normal"`
+	}{
+		Type:   l.Type.String(),
+		Normal: l.Normal,
+	})
+}
+
+func (l BucketIndexNormalLayout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		NumShards uint32 `json:"num_shards"`
+		HashType  string `json:"hash_type"`
+	}{
+		NumShards: l.NumShards,
+		HashType:  l.HashType.String(),
+	})
+}
+
+func (l BucketLogLayoutGeneration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Gen    uint64          `json:"gen"`
+		Layout BucketLogLayout `json:"layout"`
+	}{
+		Gen:    l.Gen,
+		Layout: l.Layout,
+	})
+}
+
+func (l BucketLogLayout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string               `json:"type"`
+		InIndex BucketIndexLogLayout `json:"in_index"`
+	}{
+		Type:    l.Type.String(),
+		InIndex: l.InIndex,
+	})
+}
+
+func (l BucketIndexLogLayout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Gen    uint64                  `json:"gen"`
+		Layout BucketIndexNormalLayout `json:"layout"`
+	}{
+		Gen:    l.Gen,
+		Layout: l.Layout,
+	})
+}
+
+func (r RGWUserInfo) MarshalJSON() ([]byte, error) {
+	type jsonSubuser struct {
+		ID          string `json:"id"`
+		Permissions string `json:"permissions"`
+	}
+	type jsonKey struct {
+		User      string `json:"user"`
+		AccessKey string `json:"access_key"`
+		SecretKey string `json:"secret_key"`
+	}
+	type jsonSwiftKey struct {
+		User      string `json:"user"`
+		SecretKey string `json:"secret_key"`
+	}
+	type jsonCap struct {
+		Type string `json:"type"`
+		Perm string `json:"perm"`
+	}
+	uid := r.UserID.String()
+	keyUser := func(k RGWAccessKey) string {
+		if k.Subuser == "" {
+			return uid
+		}
+		return uid + ":" + k.Subuser
+	}
+
+	names := make([]string, 0, len(r.Subusers))
+	for k := range r.Subusers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	subusers := make([]jsonSubuser, 0, len(names))
+	for _, k := range names {
+		s := r.Subusers[k]
+		id := uid
+		if s.Name != "" {
+			id += ":" + s.Name
+		}
+		subusers = append(subusers, jsonSubuser{ID: id, Permissions: permString(s.PermMask)})
+	}
+	keys := make([]jsonKey, 0, len(r.AccessKeys))
+	for _, id := range sortedAccessKeyIDs(r.AccessKeys) {
+		k := r.AccessKeys[id]
+		keys = append(keys, jsonKey{User: keyUser(k), AccessKey: k.ID, SecretKey: k.Key})
+	}
+	swiftKeys := make([]jsonSwiftKey, 0, len(r.SwiftKeys))
+	for _, id := range sortedAccessKeyIDs(r.SwiftKeys) {
+		k := r.SwiftKeys[id]
+		swiftKeys = append(swiftKeys, jsonSwiftKey{User: keyUser(k), SecretKey: k.Key})
+	}
+	capTypes := make([]string, 0, len(r.Caps))
+	for k := range r.Caps {
+		capTypes = append(capTypes, k)
+	}
+	sort.Strings(capTypes)
+	caps := make([]jsonCap, 0, len(capTypes))
+	for _, k := range capTypes {
+		caps = append(caps, jsonCap{Type: k, Perm: capString(r.Caps[k])})
+	}
+	tempURLKeyIDs := make([]int32, 0, len(r.TempURLKeys))
+	for k := range r.TempURLKeys {
+		tempURLKeyIDs = append(tempURLKeyIDs, k)
+	}
+	sort.Slice(tempURLKeyIDs, func(i, j int) bool { return tempURLKeyIDs[i] < tempURLKeyIDs[j] })
+	tempURLKeys := make([]jsonKeyVal, 0, len(tempURLKeyIDs))
+	for _, k := range tempURLKeyIDs {
+		tempURLKeys = append(tempURLKeys, jsonKeyVal{Key: k, Val: r.TempURLKeys[k]})
+	}
+	placementTags := r.PlacementTags
+	if placementTags == nil {
+		placementTags = []string{}
+	}
+	mfaIDs := r.MFAIDs
+	if mfaIDs == nil {
+		mfaIDs = []string{}
+	}
+	suspended := 0
+	if r.Suspended {
+		suspended = 1
+	}
+	return json.Marshal(struct {
+		UserID              string         `json:"user_id"`
+		DisplayName         string         `json:"display_name"`
+		Email               string         `json:"email"`
+		Suspended           int            `json:"suspended"`
+		MaxBuckets          int32          `json:"max_buckets"`
+		Subusers            []jsonSubuser  `json:"subusers"`
+		Keys                []jsonKey      `json:"keys"`
+		SwiftKeys           []jsonSwiftKey `json:"swift_keys"`
+		Caps                []jsonCap      `json:"caps"`
+		OpMask              string         `json:"op_mask"`
+		System              bool           `json:"system,omitempty"`
+		Admin               bool           `json:"admin,omitempty"`
+		DefaultPlacement    string         `json:"default_placement"`
+		DefaultStorageClass string         `json:"default_storage_class"`
+		PlacementTags       []string       `json:"placement_tags"`
+		BucketQuota         RGWQuotaInfo   `json:"bucket_quota"`
+		UserQuota           RGWQuotaInfo   `json:"user_quota"`
+		TempURLKeys         []jsonKeyVal   `json:"temp_url_keys"`
+		Type                string         `json:"type"`
+		MFAIDs              []string       `json:"mfa_ids"`
+		AssumedRoleARN      string         `json:"assumed_role_arn,omitempty"`
+	}{
+		UserID:              uid,
+		DisplayName:         r.DisplayName,
+		Email:               r.Email,
+		Suspended:           suspended,
+		MaxBuckets:          r.MaxBuckets,
+		Subusers:            subusers,
+		Keys:                keys,
+		SwiftKeys:           swiftKeys,
+		Caps:                caps,
+		OpMask:              opMaskString(r.OpMask),
+		System:              r.System,
+		Admin:               r.Admin,
+		DefaultPlacement:    r.DefaultPlacement.Name,
+		DefaultStorageClass: r.DefaultPlacement.StorageClass,
+		PlacementTags:       placementTags,
+		BucketQuota:         r.BucketQuota,
+		UserQuota:           r.UserQuota,
+		TempURLKeys:         tempURLKeys,
+		Type:                r.Type.String(),
+		MFAIDs:              mfaIDs,
+		AssumedRoleARN:      r.AssumedRoleARN,
+	})
+}
+
+func (r RGWBucketDirEntry) MarshalJSON() ([]byte, error) {
+	pending := make([]jsonKeyVal, 0, len(r.PendingMap))
+	for _, p := range r.PendingMap {
+		pending = append(pending, jsonKeyVal{Key: p.Tag, Val: p.Info})
+	}
+	return json.Marshal(struct {
+		Name           string                `json:"name"`
+		Instance       string                `json:"instance"`
+		Ver            RGWBucketEntryVer     `json:"ver"`
+		Locator        string                `json:"locator"`
+		Exists         bool                  `json:"exists"`
+		Meta           RGWBucketDirEntryMeta `json:"meta"`
+		Tag            string                `json:"tag"`
+		Flags          uint16                `json:"flags"`
+		PendingMap     []jsonKeyVal          `json:"pending_map"`
+		VersionedEpoch uint64                `json:"versioned_epoch"`
+	}{
+		Name:           r.Key.Name,
+		Instance:       r.Key.Instance,
+		Ver:            r.Ver,
+		Locator:        r.Locator,
+		Exists:         r.Exists,
+		Meta:           r.Meta,
+		Tag:            r.Tag,
+		Flags:          r.Flags,
+		PendingMap:     pending,
+		VersionedEpoch: r.VersionedEpoch,
+	})
+}
+
+func (r RGWBucketEntryVer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Pool  int64  `json:"pool"`
+		Epoch uint64 `json:"epoch"`
+	}{
+		Pool:  r.Pool,
+		Epoch: r.Epoch,
+	})
+}
+
+func (r RGWBucketDirEntryMeta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Category         uint8  `json:"category"`
+		Size             uint64 `json:"size"`
+		Mtime            utime  `json:"mtime"`
+		ETag             string `json:"etag"`
+		StorageClass     string `json:"storage_class"`
+		Owner            string `json:"owner"`
+		OwnerDisplayName string `json:"owner_display_name"`
+		ContentType      string `json:"content_type"`
+		AccountedSize    uint64 `json:"accounted_size"`
+		UserData         string `json:"user_data"`
+		Appendable       bool   `json:"appendable"`
+	}{
+		Category:         uint8(r.Category),
+		Size:             r.Size,
+		Mtime:            utime(r.Mtime),
+		ETag:             r.ETag,
+		StorageClass:     r.StorageClass,
+		Owner:            r.Owner,
+		OwnerDisplayName: r.OwnerDisplayName,
+		ContentType:      r.ContentType,
+		AccountedSize:    r.AccountedSize,
+		UserData:         r.UserData,
+		Appendable:       r.Appendable,
+	})
+}
+
+func (r RGWBucketPendingInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		State     uint8 `json:"state"`
+		Timestamp utime `json:"timestamp"`
+		Op        uint8 `json:"op"`
+	}{
+		State:     uint8(r.State),
+		Timestamp: utime(r.Timestamp),
+		Op:        uint8(r.Op),
+	})
+}
+
+func (r RGWBucketDirHeader) MarshalJSON() ([]byte, error) {
+	categories := r.sortedCategories()
+	stats := make([]interface{}, 0, 2*len(categories))
+	for _, c := range categories {
+		stats = append(stats, uint8(c), r.Stats[c])
+	}
+	return json.Marshal(struct {
+		Ver         uint64                    `json:"ver"`
+		MasterVer   uint64                    `json:"master_ver"`
+		Stats       []interface{}             `json:"stats"`
+		NewInstance ClsRGWBucketInstanceEntry `json:"new_instance"`
+	}{
+		Ver:         r.Ver,
+		MasterVer:   r.MasterVer,
+		Stats:       stats,
+		NewInstance: r.NewInstance,
+	})
+}
+
+func (r RGWBucketCategoryStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		TotalSize        uint64 `json:"total_size"`
+		TotalSizeRounded uint64 `json:"total_size_rounded"`
+		NumEntries       uint64 `json:"num_entries"`
+		ActualSize       uint64 `json:"actual_size"`
+	}{
+		TotalSize:        r.TotalSize,
+		TotalSizeRounded: r.TotalSizeRounded,
+		NumEntries:       r.NumEntries,
+		ActualSize:       r.ActualSize,
+	})
+}
+
+func (r ClsRGWBucketInstanceEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ReshardStatus       string `json:"reshard_status"`
+		NewBucketInstanceID string `json:"new_bucket_instance_id"`
+		NumShards           int32  `json:"num_shards"`
+	}{
+		ReshardStatus:       r.ReshardStatus.String(),
+		NewBucketInstanceID: r.NewBucketInstanceID,
+		NumShards:           r.NumShards,
+	})
+}
+
+func (r ClsRGWObjKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name     string `json:"name"`
+		Instance string `json:"instance"`
+	}{
+		Name:     r.Name,
+		Instance: r.Instance,
+	})
+}
+
+func (r RGWBucketOLHLogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Epoch        uint64       `json:"epoch"`
+		Op           string       `json:"op"`
+		OpTag        string       `json:"op_tag"`
+		Key          ClsRGWObjKey `json:"key"`
+		DeleteMarker bool         `json:"delete_marker"`
+	}{
+		Epoch:        r.Epoch,
+		Op:           r.Op.String(),
+		OpTag:        r.OpTag,
+		Key:          r.Key,
+		DeleteMarker: r.DeleteMarker,
+	})
+}
+
+func (r RGWBucketOLHEntry) MarshalJSON() ([]byte, error) {
+	pending := make([]jsonKeyVal, 0, len(r.PendingLog))
+	for _, l := range r.PendingLog {
+		entries := l.Entries
+		if entries == nil {
+			entries = []RGWBucketOLHLogEntry{}
+		}
+		pending = append(pending, jsonKeyVal{Key: l.Epoch, Val: entries})
+	}
+	return json.Marshal(struct {
+		Key            ClsRGWObjKey `json:"key"`
+		DeleteMarker   bool         `json:"delete_marker"`
+		Epoch          uint64       `json:"epoch"`
+		PendingLog     []jsonKeyVal `json:"pending_log"`
+		Tag            string       `json:"tag"`
+		Exists         bool         `json:"exists"`
+		PendingRemoval bool         `json:"pending_removal"`
+	}{
+		Key:            r.Key,
+		DeleteMarker:   r.DeleteMarker,
+		Epoch:          r.Epoch,
+		PendingLog:     pending,
+		Tag:            r.Tag,
+		Exists:         r.Exists,
+		PendingRemoval: r.PendingRemoval,
+	})
+}
+
+func (r RGWBILogEntry) MarshalJSON() ([]byte, error) {
+	type zoneSetEntry struct {
+		Entry string `json:"entry"`
+	}
+	zones := make([]zoneSetEntry, 0, len(r.ZonesTrace))
+	for _, z := range r.ZonesTrace {
+		zones = append(zones, zoneSetEntry{Entry: z})
+	}
+	return json.Marshal(struct {
+		OpID             string            `json:"op_id"`
+		OpTag            string            `json:"op_tag"`
+		Op               string            `json:"op"`
+		Object           string            `json:"object"`
+		Instance         string            `json:"instance"`
+		State            string            `json:"state"`
+		IndexVer         uint64            `json:"index_ver"`
+		Timestamp        utimeNsec         `json:"timestamp"`
+		Ver              RGWBucketEntryVer `json:"ver"`
+		BILogFlags       uint16            `json:"bilog_flags"`
+		Versioned        bool              `json:"versioned"`
+		Owner            string            `json:"owner"`
+		OwnerDisplayName string            `json:"owner_display_name"`
+		ZonesTrace       []zoneSetEntry    `json:"zones_trace"`
+	}{
+		OpID:             r.ID,
+		OpTag:            r.Tag,
+		Op:               r.Op.String(),
+		Object:           r.Object,
+		Instance:         r.Instance,
+		State:            r.State.String(),
+		IndexVer:         r.IndexVer,
+		Timestamp:        utimeNsec(r.Timestamp),
+		Ver:              r.Ver,
+		BILogFlags:       r.BILogFlags,
+		Versioned:        r.BILogFlags&BILogFlagVersionedOp != 0,
+		Owner:            r.Owner,
+		OwnerDisplayName: r.OwnerDisplayName,
+		ZonesTrace:       zones,
+	})
+}
+
+// MarshalJSON marshals the entry like `radosgw-admin bi list`. The 0x80 byte
+// that starts the keys of the special namespaces is not valid UTF-8 and is
+// marshaled as U+0080.
+func (r BIEntry) MarshalJSON() ([]byte, error) {
+	idx := r.Idx
+	if strings.HasPrefix(idx, biPrefixChar) {
+		idx = "\u0080" + idx[len(biPrefixChar):]
+	}
+	return json.Marshal(struct {
+		Type  string      `json:"type"`
+		Idx   string      `json:"idx"`
+		Entry interface{} `json:"entry"`
+	}{
+		Type:  r.Type.String(),
+		Idx:   idx,
+		Entry: r.Entry,
+	})
+}
+
+func (r RGWObjTags) MarshalJSON() ([]byte, error) {
+	tags := r.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return json.Marshal(struct {
+		TagSet map[string]string `json:"tagset"`
+	}{
+		TagSet: tags,
+	})
+}
+
+func (r RGWCompressionInfo) MarshalJSON() ([]byte, error) {
+	blocks := r.Blocks
+	if blocks == nil {
+		blocks = []CompressionBlock{}
+	}
+	return json.Marshal(struct {
+		CompressionType   string             `json:"compression_type"`
+		OrigSize          uint64             `json:"orig_size"`
+		CompressorMessage *int32             `json:"compressor_message,omitempty"`
+		Blocks            []CompressionBlock `json:"blocks"`
+	}{
+		CompressionType:   r.CompressionType,
+		OrigSize:          r.OrigSize,
+		CompressorMessage: r.CompressorMessage,
+		Blocks:            blocks,
+	})
+}
+
+func (r CompressionBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		OldOfs uint64 `json:"old_ofs"`
+		NewOfs uint64 `json:"new_ofs"`
+		Len    uint64 `json:"len"`
+	}{
+		OldOfs: r.OldOfs,
+		NewOfs: r.NewOfs,
+		Len:    r.Len,
+	})
+}
+
+func (r RGWOLHInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Target  RGWObj `json:"target"`
+		Removed bool   `json:"removed"`
+	}{
+		Target:  r.Target,
+		Removed: r.Removed,
+	})
+}
+
+// MarshalJSON marshals the attrs that are set; there is no Ceph dump() of
+// an object's attrs. Raw attrs are marshaled as base64.
+func (r ObjectAttrs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Manifest     *RGWObjManifest         `json:"manifest,omitempty"`
+		ACL          *RGWAccessControlPolicy `json:"acl,omitempty"`
+		ETag         string                  `json:"etag,omitempty"`
+		ContentType  string                  `json:"content_type,omitempty"`
+		TailTag      string                  `json:"tail_tag,omitempty"`
+		IDTag        string                  `json:"idtag,omitempty"`
+		PGVer        uint64                  `json:"pg_ver,omitempty"`
+		SourceZone   uint32                  `json:"source_zone,omitempty"`
+		StorageClass string                  `json:"storage_class,omitempty"`
+		UserMeta     map[string]string       `json:"user_meta,omitempty"`
+		Tags         *RGWObjTags             `json:"tags,omitempty"`
+		Compression  *RGWCompressionInfo     `json:"compression,omitempty"`
+		Crypt        map[string]string       `json:"crypt,omitempty"`
+		OLH          *RGWOLHInfo             `json:"olh_info,omitempty"`
+		Raw          map[string][]byte       `json:"raw,omitempty"`
+	}{
+		Manifest:     r.Manifest,
+		ACL:          r.ACL,
+		ETag:         r.ETag,
+		ContentType:  r.ContentType,
+		TailTag:      r.TailTag,
+		IDTag:        r.IDTag,
+		PGVer:        r.PGVer,
+		SourceZone:   r.SourceZone,
+		StorageClass: r.StorageClass,
+		UserMeta:     r.UserMeta,
+		Tags:         r.Tags,
+		Compression:  r.Compression,
+		Crypt:        r.Crypt,
+		OLH:          r.OLH,
+		Raw:          r.Raw,
+	})
+}
+
+func (r RGWAccessControlPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ACL   RGWAccessControlList `json:"acl"`
+		Owner ACLOwner             `json:"owner"`
+	}{
+		ACL:   r.ACL,
+		Owner: r.Owner,
+	})
+}
+
+func (r ACLOwner) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	}{
+		ID:          r.ID.String(),
+		DisplayName: r.DisplayName,
+	})
+}
+
+func (r RGWAccessControlList) MarshalJSON() ([]byte, error) {
+	type userEntry struct {
+		User string `json:"user"`
+		ACL  int32  `json:"acl"`
+	}
+	type groupEntry struct {
+		Group uint32 `json:"group"`
+		ACL   int32  `json:"acl"`
+	}
+	type grantEntry struct {
+		ID    string   `json:"id"`
+		Grant ACLGrant `json:"grant"`
+	}
+	users := make([]string, 0, len(r.UserMap))
+	for u := range r.UserMap {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	userMap := make([]userEntry, 0, len(users))
+	for _, u := range users {
+		userMap = append(userMap, userEntry{User: u, ACL: r.UserMap[u]})
+	}
+	groups := r.sortedGroups()
+	groupMap := make([]groupEntry, 0, len(groups))
+	for _, g := range groups {
+		groupMap = append(groupMap, groupEntry{Group: uint32(g), ACL: r.GroupMap[g]})
+	}
+	grantMap := make([]grantEntry, 0, len(r.GrantMap))
+	for _, g := range r.GrantMap {
+		grantMap = append(grantMap, grantEntry{ID: g.Key, Grant: g.Grant})
+	}
+	return json.Marshal(struct {
+		UserMap  []userEntry  `json:"acl_user_map"`
+		GroupMap []groupEntry `json:"acl_group_map"`
+		GrantMap []grantEntry `json:"grant_map"`
+	}{
+		UserMap:  userMap,
+		GroupMap: groupMap,
+		GrantMap: grantMap,
+	})
+}
+
+func (r ACLGrant) MarshalJSON() ([]byte, error) {
+	type granteeType struct {
+		Type uint32 `json:"type"`
+	}
+	type permission struct {
+		Flags int32 `json:"flags"`
+	}
+	return json.Marshal(struct {
+		Type       granteeType `json:"type"`
+		ID         string      `json:"id"`
+		Email      string      `json:"email"`
+		Permission permission  `json:"permission"`
+		Name       string      `json:"name"`
+		Group      uint32      `json:"group"`
+		URLSpec    string      `json:"url_spec"`
+	}{
+		Type:       granteeType{Type: uint32(r.Type)},
+		ID:         r.ID.String(),
+		Email:      r.Email,
+		Permission: permission{Flags: r.Permission},
+		Name:       r.Name,
+		Group:      uint32(r.Group),
+		URLSpec:    r.URLSpec,
+	})
+}
+
+// utime formats a time like utime_t::gmtime, which is what encode_json uses
+// for utime_t and ceph::real_time values.
+type utime time.Time
+
+func (t utime) String() string {
+	tt := time.Time(t)
+	if tt.IsZero() {
+		return "0.000000"
+	}
+	// Like Ceph, print relative times (less than ten years) as seconds.
+	if tt.Unix() < 60*60*24*365*10 {
+		return fmt.Sprintf("%d.%06d", tt.Unix(), tt.Nanosecond()/1000)
+	}
+	return tt.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+func (t utime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// utimeNsec formats a time like utime_t::gmtime_nsec.
+type utimeNsec time.Time
+
+func (t utimeNsec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format("2006-01-02T15:04:05.000000000Z"))
+}
+
+// String returns the pool as rgw_pool::to_str does, escaping ':' in the name
+// and namespace.
+func (r RGWPool) String() string {
+	name := escapeRGWStr(r.Name, ':')
+	if r.NS == "" {
+		return name
+	}
+	return name + ":" + escapeRGWStr(r.NS, ':')
+}
+
+func escapeRGWStr(s string, special byte) string {
+	if strings.IndexByte(s, '\\') < 0 && strings.IndexByte(s, special) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' || s[i] == special {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRGWObjManifestJSONLayout(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_2")
+	assert.NoError(t, err)
+	manifest, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+
+	out, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), `{"objs":[],"obj_size":`), "%s", out)
+
+	var v struct {
+		Rules []struct {
+			Key uint64             `json:"key"`
+			Val RGWObjManifestRule `json:"val"`
+		} `json:"rules"`
+		BeginIter struct {
+			CurPartID int32 `json:"cur_part_id"`
+			Location  struct {
+				PlacementRule string `json:"placement_rule"`
+				IsRaw         bool   `json:"is_raw"`
+			} `json:"location"`
+		} `json:"begin_iter"`
+		TailPlacement struct {
+			Bucket struct {
+				ExplicitPlacement struct {
+					DataPool string `json:"data_pool"`
+				} `json:"explicit_placement"`
+			} `json:"bucket"`
+		} `json:"tail_placement"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &v))
+	assert.Len(t, v.Rules, len(manifest.Rules))
+	for i, k := range manifest.Rules.keys() {
+		assert.Equal(t, k, v.Rules[i].Key)
+	}
+	assert.Equal(t, manifest.BeginIter.CurPartID, v.BeginIter.CurPartID)
+	assert.Equal(t, manifest.BeginIter.Location.PlacementRule.String(), v.BeginIter.Location.PlacementRule)
+}
+
+func TestUserBucketEntryJSONLayout(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	entry, err := DecodeUserBucketEntry(data)
+	assert.NoError(t, err)
+
+	out, err := json.Marshal(entry)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), `{"bucket":{"name":"gzim","marker":`), "%s", out)
+	assert.Contains(t, string(out), `"size":1401466738031,"size_rounded":1407282757632,"creation_time":"2019-02-20T06:54:24.584127Z"`)
+	assert.Contains(t, string(out), `"count":3278102,"user_stats_sync":true}`)
+}
+
+func TestUTimeString(t *testing.T) {
+	assert.Equal(t, "0.000000", utime(time.Time{}).String())
+	assert.Equal(t, "12.000345", utime(time.Unix(12, 345000)).String())
+	assert.Equal(t, "2020-06-01T10:20:30.000001Z", utime(time.Date(2020, 6, 1, 10, 20, 30, 1000, time.UTC)).String())
+}
+
+func TestRGWPoolString(t *testing.T) {
+	assert.Equal(t, "default.rgw.buckets.data", RGWPool{Name: "default.rgw.buckets.data"}.String())
+	assert.Equal(t, `a\:b:ns`, RGWPool{Name: "a:b", NS: "ns"}.String())
+}
@@ -1,7 +1,6 @@
 package decoder
 
 import (
-	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -118,11 +117,13 @@ type RGWObjKey struct {
 	Instance string
 }
 
-func DecodeRGWObjManifest(data []byte) (*RGWObjManifest, error) {
-	d := &decoder{
-		Data: data,
+func DecodeRGWObjManifest(data []byte, opts ...DecodeOptions) (*RGWObjManifest, error) {
+	d := NewDecoder(data, opts...)
+	m, err := d.DecodeRGWObjManifest()
+	if err != nil {
+		return nil, err
 	}
-	return d.decodeRGWObjManifest()
+	return m, d.Done()
 }
 
 func initObjIterator(r *RGWObjManifest) *ObjIterator {
@@ -263,14 +264,22 @@ func (o *ObjIterator) iterate() {
 	o.updataLocation()
 }
 
-func (r ruleIterator) begin() rulePair {
-	var keys []uint64
+func (r ruleIterator) keys() []uint64 {
+	keys := make([]uint64, 0, len(r))
 	for k := range r {
 		keys = append(keys, k)
 	}
 	sort.Slice(keys, func(i, j int) bool {
 		return keys[i] < keys[j]
 	})
+	return keys
+}
+
+func (r ruleIterator) begin() rulePair {
+	keys := r.keys()
+	if len(keys) == 0 {
+		return r.end()
+	}
 	return rulePair{
 		First:  keys[0],
 		Second: r[keys[0]],
@@ -284,14 +293,7 @@ func (r ruleIterator) end() rulePair {
 }
 
 func (r ruleIterator) backward(p *rulePair) {
-	var keys []uint64
-
-	for k := range r {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i] < keys[j]
-	})
+	keys := r.keys()
 
 	end := len(keys) - 1
 
@@ -316,13 +318,7 @@ func (r ruleIterator) backward(p *rulePair) {
 }
 
 func (r ruleIterator) forward(p *rulePair) {
-	var keys []uint64
-	for k := range r {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i] < keys[j]
-	})
+	keys := r.keys()
 	var pos int
 	for i, k := range keys {
 		if k == p.First {
@@ -342,13 +338,7 @@ func (r ruleIterator) forward(p *rulePair) {
 }
 
 func (r ruleIterator) upperBound(key uint64) rulePair {
-	var keys []uint64
-	for k := range r {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i] < keys[j]
-	})
+	keys := r.keys()
 	for i, k := range keys {
 		if key < k {
 			return rulePair{
@@ -384,7 +374,13 @@ func (r *RGWObjManifest) RadosObjectsKeys() []string {
 				r.BeginIter.Location.Obj.Key.Name)
 		}
 		keys = append(keys, key)
+		ofs := r.BeginIter.Ofs
 		r.BeginIter.iterate()
+		// A corrupted rule with a zero stripe size never moves the
+		// iterator forward.
+		if r.BeginIter.Ofs == ofs {
+			break
+		}
 	}
 	return keys
 }
@@ -408,10 +404,10 @@ func (r *RGWObjManifest) getImplicitLocation(cur_part_id, cur_stripe int32, ofs
 	}
 	if cur_part_id == 0 {
 		if ofs < r.MaxHeapSize {
-			location.PlacementRule = r.HeadPlacementRule
 			*location = RGWObjSelect{
-				Obj:   r.Obj,
-				IsRaw: false,
+				Obj:           r.Obj,
+				PlacementRule: r.HeadPlacementRule,
+				IsRaw:         false,
 			}
 			return
 		} else {
@@ -437,10 +433,10 @@ func (r *RGWObjManifest) getImplicitLocation(cur_part_id, cur_stripe int32, ofs
 	}
 	loc.Key.Instance = r.TailInstance
 
-	location.PlacementRule = r.TailPlacement.PlacementRule
 	*location = RGWObjSelect{
-		Obj:   loc,
-		IsRaw: false,
+		Obj:           loc,
+		PlacementRule: r.TailPlacement.PlacementRule,
+		IsRaw:         false,
 	}
 }
 
@@ -480,10 +476,11 @@ func (r *RGWPlacementRule) fromStr(s string) {
 	r.StorageClass = s[pos+1:]
 }
 
-func (d *decoder) decodeRGWPlacementRule() (*RGWPlacementRule, error) {
+// DecodeRGWPlacementRule decodes a rgw_placement_rule.
+func (d *Decoder) DecodeRGWPlacementRule() (*RGWPlacementRule, error) {
 	var r RGWPlacementRule
 
-	s, err := d.decodeString()
+	s, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
@@ -491,85 +488,98 @@ func (d *decoder) decodeRGWPlacementRule() (*RGWPlacementRule, error) {
 	return &r, nil
 }
 
-func (d *decoder) decodeRGWObjKey() (*RGWObjKey, error) {
+// DecodeRGWObjKey decodes a rgw_obj_key.
+func (d *Decoder) DecodeRGWObjKey() (*RGWObjKey, error) {
 	var r RGWObjKey
-	structV, _, _, err := d.decodeStart(2)
+	structV, _, structEnd, err := d.DecodeStart("rgw_obj_key", 2)
 	if err != nil {
 		return nil, err
 	}
-	name, err := d.decodeString()
+	d.Field("name")
+	name, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	r.Name = name
-	instance, err := d.decodeString()
+	d.Field("instance")
+	instance, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	r.Instance = instance
 	if structV >= 2 {
-		ns, err := d.decodeString()
+		d.Field("ns")
+		ns, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.NS = ns
 	}
-	return &r, nil
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWPool() (*RGWPool, error) {
+// DecodeRGWPool decodes a rgw_pool.
+func (d *Decoder) DecodeRGWPool() (*RGWPool, error) {
 	var r RGWPool
 
-	_, structEnd, err := d.decodeStartLegacyCompatLen(10, 3, 3)
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_pool", 10, 3, 3)
 	if err != nil {
 		return nil, err
 	}
-	name, err := d.decodeString()
+	d.Field("name")
+	name, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	r.Name = name
-	ns, err := d.decodeString()
+	d.Field("ns")
+	ns, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	r.NS = ns
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWBucket() (*RGWBucket, error) {
+// DecodeRGWBucket decodes a rgw_bucket.
+func (d *Decoder) DecodeRGWBucket() (*RGWBucket, error) {
 	var r RGWBucket
 
-	structV, structEnd, err := d.decodeStartLegacyCompatLen(10, 3, 3)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_bucket", 10, 3, 3)
 	if err != nil {
 		return nil, err
 	}
-	name, err := d.decodeString()
+	d.Field("name")
+	name, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	r.Name = name
 	if structV < 10 {
-		pname, err := d.decodeString()
+		d.Field("data_pool")
+		pname, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.ExplicitPlacement.DataPool.Name = pname
 	}
 	if structV >= 2 {
-		mk, err := d.decodeString()
+		d.Field("marker")
+		mk, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Marker = mk
 		if structV <= 3 {
-			id, err := d.decodeU64()
+			d.Field("bucket_id")
+			id, err := d.DecodeU64()
 			if err != nil {
 				return nil, err
 			}
 			r.BucketID = fmt.Sprintf("%d", id)
 		} else {
-			bid, err := d.decodeString()
+			d.Field("bucket_id")
+			bid, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
@@ -578,16 +588,20 @@ func (d *decoder) decodeRGWBucket() (*RGWBucket, error) {
 	}
 	if structV < 10 {
 		if structV >= 5 {
-			name, err := d.decodeString()
+			d.Field("index_pool")
+			name, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
-			r.ExplicitPlacement.DataPool.Name = name
+			r.ExplicitPlacement.IndexPool.Name = name
 		} else {
 			r.ExplicitPlacement.IndexPool = r.ExplicitPlacement.DataPool
+			d.Field("index_pool")
+			d.Warn(WarnDefaultedIndexPool, "struct_v %d has no index pool, using data pool %q", structV, r.ExplicitPlacement.DataPool.Name)
 		}
 		if structV >= 7 {
-			name, err := d.decodeString()
+			d.Field("data_extra_pool")
+			name, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
@@ -595,28 +609,36 @@ func (d *decoder) decodeRGWBucket() (*RGWBucket, error) {
 		}
 	}
 	if structV >= 8 {
-		tenant, err := d.decodeString()
+		d.Field("tenant")
+		tenant, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Tenant = tenant
 	}
 	if structV >= 10 {
-		decodeExplicit := d.decodeBool()
+		d.Field("explicit_placement")
+		decodeExplicit, err := d.DecodeBool()
+		if err != nil {
+			return nil, err
+		}
 		if decodeExplicit {
-			dataPool, err := d.decodeRGWPool()
+			d.Field("explicit_placement.data_pool")
+			dataPool, err := d.DecodeRGWPool()
 			if err != nil {
 				return nil, err
 			}
 			r.ExplicitPlacement.DataPool = *dataPool
 
-			extraPool, err := d.decodeRGWPool()
+			d.Field("explicit_placement.data_extra_pool")
+			extraPool, err := d.DecodeRGWPool()
 			if err != nil {
 				return nil, err
 			}
 			r.ExplicitPlacement.DataExtraPool = *extraPool
 
-			indexPool, err := d.decodeRGWPool()
+			d.Field("explicit_placement.index_pool")
+			indexPool, err := d.DecodeRGWPool()
 			if err != nil {
 				return nil, err
 			}
@@ -624,235 +646,276 @@ func (d *decoder) decodeRGWBucket() (*RGWBucket, error) {
 		}
 	}
 
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWObj() (*RGWObj, error) {
+// DecodeRGWObj decodes a rgw_obj.
+func (d *Decoder) DecodeRGWObj() (*RGWObj, error) {
 	var r RGWObj
-	structV, structEnd, err := d.decodeStartLegacyCompatLen(6, 3, 3)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("rgw_obj", 6, 3, 3)
 	if err != nil {
 		return nil, err
 	}
 
 	if structV < 6 {
-		name, err := d.decodeString()
+		d.Field("bucket.name")
+		name, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Bucket.Name = name
-		if _, err = d.decodeString(); err != nil {
+		d.Field("loc")
+		if _, err = d.DecodeString(); err != nil {
 			return nil, err
 		}
-		ns, err := d.decodeString()
+		d.Field("key.ns")
+		ns, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Key.NS = ns
 
-		keyName, err := d.decodeString()
+		d.Field("key.name")
+		keyName, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Key.Name = keyName
 
 		if structV >= 2 {
-			bucket, err := d.decodeRGWBucket()
+			d.Field("bucket")
+			bucket, err := d.DecodeRGWBucket()
 			if err != nil {
 				return nil, err
 			}
 			r.Bucket = *bucket
 		}
 		if structV >= 4 {
-			ins, err := d.decodeString()
+			d.Field("key.instance")
+			ins, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
 			r.Key.Instance = ins
 		}
 		if r.Key.NS == "" && r.Key.Instance == "" {
-			r.Key.Name = strings.TrimPrefix(r.Key.Name, "_")
+			if strings.HasPrefix(r.Key.Name, "_") {
+				d.Warn(WarnTrimmedKeyName, "trimmed the \"_\" prefix of %q", r.Key.Name)
+				r.Key.Name = r.Key.Name[1:]
+			}
 		} else {
 			if structV >= 5 {
-				name, err := d.decodeString()
+				d.Field("key.name")
+				name, err := d.DecodeString()
 				if err != nil {
 					return nil, err
 				}
 				r.Key.Name = name
 			} else {
 				i := strings.Index(r.Key.Name, "_")
+				if i < 0 && d.opts.Mode != ModeBestEffort {
+					return nil, d.NewError(ErrIncompatible)
+				}
 				if i < 0 {
-					return nil, errors.New("DECODE_ERR_PAST")
+					d.Warn(WarnTrimmedKeyName, "no namespace prefix in %q, keeping the name", r.Key.Name)
+				} else {
+					d.Warn(WarnTrimmedKeyName, "trimmed the namespace prefix of %q", r.Key.Name)
 				}
 				r.Key.Name = r.Key.Name[i+1:]
 			}
 		}
 	} else {
-		bucket, err := d.decodeRGWBucket()
+		d.Field("bucket")
+		bucket, err := d.DecodeRGWBucket()
 		if err != nil {
 			return nil, err
 		}
 		r.Bucket = *bucket
-		ns, err := d.decodeString()
+		d.Field("key.ns")
+		ns, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Key.NS = ns
 
-		keyName, err := d.decodeString()
+		d.Field("key.name")
+		keyName, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Key.Name = keyName
 
-		ins, err := d.decodeString()
+		d.Field("key.instance")
+		ins, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Key.Instance = ins
 	}
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWObjManifestRule() (*RGWObjManifestRule, error) {
+// DecodeRGWObjManifestRule decodes a RGWObjManifestRule.
+func (d *Decoder) DecodeRGWObjManifestRule() (*RGWObjManifestRule, error) {
 	var r RGWObjManifestRule
 
-	structV, _, structEnd, err := d.decodeStart(2)
+	structV, _, structEnd, err := d.DecodeStart("RGWObjManifestRule", 2)
 	if err != nil {
 		return nil, err
 	}
 
-	spn, err := d.decodeU32()
+	d.Field("start_part_num")
+	spn, err := d.DecodeU32()
 	if err != nil {
 		return nil, err
 	}
 	r.StartPartNum = spn
 
-	startOfs, err := d.decodeU64()
+	d.Field("start_ofs")
+	startOfs, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.StartOfs = startOfs
 
-	ps, err := d.decodeU64()
+	d.Field("part_size")
+	ps, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.PartSize = ps
 
-	sms, err := d.decodeU64()
+	d.Field("stripe_max_size")
+	sms, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.StripeMaxSize = sms
 
 	if structV >= 2 {
-		op, err := d.decodeString()
+		d.Field("override_prefix")
+		op, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.OverridePrefix = op
 	}
 
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWObjManifestPart() (*RGWObjManifestPart, error) {
+// DecodeRGWObjManifestPart decodes a RGWObjManifestPart.
+func (d *Decoder) DecodeRGWObjManifestPart() (*RGWObjManifestPart, error) {
 	var r RGWObjManifestPart
 
-	_, structEnd, err := d.decodeStartLegacyCompatLen(2, 2, 2)
+	_, structEnd, err := d.DecodeStartLegacyCompatLen("RGWObjManifestPart", 2, 2, 2)
 	if err != nil {
 		return nil, err
 	}
-	robj, err := d.decodeRGWObj()
+	d.Field("loc")
+	robj, err := d.DecodeRGWObj()
 	if err != nil {
 		return nil, err
 	}
 	r.Loc = *robj
 
-	locOfs, err := d.decodeU64()
+	d.Field("loc_ofs")
+	locOfs, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.LocOfs = locOfs
 
-	size, err := d.decodeU64()
+	d.Field("size")
+	size, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.Size = size
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
-func (d *decoder) decodeRGWObjManifest() (*RGWObjManifest, error) {
+// DecodeRGWObjManifest decodes a RGWObjManifest.
+func (d *Decoder) DecodeRGWObjManifest() (*RGWObjManifest, error) {
 	r := RGWObjManifest{
 		Objs:  make(map[uint64]RGWObjManifestPart),
 		Rules: make(map[uint64]RGWObjManifestRule),
 	}
-	structV, structEnd, err := d.decodeStartLegacyCompatLen(7, 2, 2)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("RGWObjManifest", 7, 2, 2)
 	if err != nil {
 		return nil, err
 	}
 
-	objSize, err := d.decodeU64()
+	d.Field("obj_size")
+	objSize, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	r.ObjSize = objSize
-	l, err := d.decodeU32()
-	if err != nil {
-		return nil, err
-	}
-	for i := uint32(0); i < l; i++ {
-		k, err := d.decodeU64()
+	err = d.DecodeMap("objs", func(i uint32) error {
+		k, err := d.DecodeU64()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		part, err := d.decodeRGWObjManifestPart()
+		part, err := d.DecodeRGWObjManifestPart()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		r.Objs[k] = *part
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	if structV > 3 {
-		r.ExplicitObjs = d.decodeBool()
-		obj, err := d.decodeRGWObj()
+		d.Field("explicit_objs")
+		explicitObjs, err := d.DecodeBool()
+		if err != nil {
+			return nil, err
+		}
+		r.ExplicitObjs = explicitObjs
+		d.Field("obj")
+		obj, err := d.DecodeRGWObj()
 		if err != nil {
 			return nil, err
 		}
 		r.Obj = *obj
 
-		hs, err := d.decodeU64()
+		d.Field("head_size")
+		hs, err := d.DecodeU64()
 		if err != nil {
 			return nil, err
 		}
 		r.HeadSize = hs
 
-		mhs, err := d.decodeU64()
+		d.Field("max_head_size")
+		mhs, err := d.DecodeU64()
 		if err != nil {
 			return nil, err
 		}
 		r.MaxHeapSize = mhs
 
-		prefix, err := d.decodeString()
+		d.Field("prefix")
+		prefix, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		r.Prefix = prefix
 
-		l, err := d.decodeU32()
-		if err != nil {
-			return nil, err
-		}
-		for i := uint32(0); i < l; i++ {
-			k, err := d.decodeU64()
+		err = d.DecodeMap("rules", func(i uint32) error {
+			k, err := d.DecodeU64()
 			if err != nil {
-				return nil, err
+				return err
 			}
-			rule, err := d.decodeRGWObjManifestRule()
+			rule, err := d.DecodeRGWObjManifestRule()
 			if err != nil {
-				return nil, err
+				return err
 			}
 			r.Rules[k] = *rule
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 	if r.ExplicitObjs && r.HeadSize > 0 && len(r.Objs) > 0 {
@@ -862,19 +925,27 @@ func (d *decoder) decodeRGWObjManifest() (*RGWObjManifest, error) {
 			o.Loc = r.Obj
 			o.Size = r.HeadSize
 			r.Objs[0] = o
+			d.Field("objs")
+			d.Warn(WarnRewrittenHead, "replaced part %q with the head object", obj0.getOID())
 		}
 	}
 
 	if structV >= 4 {
 		if structV < 6 {
-			bucket, err := d.decodeRGWBucket()
+			d.Field("tail_placement.bucket")
+			bucket, err := d.DecodeRGWBucket()
 			if err != nil {
 				return nil, err
 			}
 			r.TailPlacement.Bucket = *bucket
 		} else {
-			if d.decodeBool() {
-				bucket, err := d.decodeRGWBucket()
+			d.Field("tail_placement.bucket")
+			encodeTailBucket, err := d.DecodeBool()
+			if err != nil {
+				return nil, err
+			}
+			if encodeTailBucket {
+				bucket, err := d.DecodeRGWBucket()
 				if err != nil {
 					return nil, err
 				}
@@ -887,14 +958,20 @@ func (d *decoder) decodeRGWObjManifest() (*RGWObjManifest, error) {
 
 	if structV >= 5 {
 		if structV < 6 {
-			ins, err := d.decodeString()
+			d.Field("tail_instance")
+			ins, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
 			r.TailInstance = ins
 		} else {
-			if d.decodeBool() {
-				ins, err := d.decodeString()
+			d.Field("tail_instance")
+			encodeTailInstance, err := d.DecodeBool()
+			if err != nil {
+				return nil, err
+			}
+			if encodeTailInstance {
+				ins, err := d.DecodeString()
 				if err != nil {
 					return nil, err
 				}
@@ -907,20 +984,22 @@ func (d *decoder) decodeRGWObjManifest() (*RGWObjManifest, error) {
 		r.TailInstance = r.Obj.Key.Instance
 	}
 	if structV >= 7 {
-		hpr, err := d.decodeRGWPlacementRule()
+		d.Field("head_placement_rule")
+		hpr, err := d.DecodeRGWPlacementRule()
 		if err != nil {
 			return nil, err
 		}
 		r.HeadPlacementRule = *hpr
 
-		tpr, err := d.decodeRGWPlacementRule()
+		d.Field("tail_placement.placement_rule")
+		tpr, err := d.DecodeRGWPlacementRule()
 		if err != nil {
 			return nil, err
 		}
 		r.TailPlacement.PlacementRule = *tpr
 	}
 	r.updateIterators()
-	return &r, d.decodeFinish(structEnd)
+	return &r, d.DecodeFinish(structEnd)
 }
 
 func minuint64(a, b uint64) uint64 {
@@ -929,3 +1008,142 @@ func minuint64(a, b uint64) uint64 {
 	}
 	return a
 }
+
+func (r RGWPlacementRule) String() string {
+	if r.StorageClass == "" || r.StorageClass == "STANDARD" {
+		return r.Name
+	}
+	return r.Name + "/" + r.StorageClass
+}
+
+func (e *Encoder) EncodeRGWPlacementRule(r *RGWPlacementRule) {
+	e.EncodeString(r.String())
+}
+
+func (e *Encoder) EncodeRGWObjKey(r *RGWObjKey) {
+	start := e.EncodeStart(2, 1)
+	e.EncodeString(r.Name)
+	e.EncodeString(r.Instance)
+	e.EncodeString(r.NS)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWPool(r *RGWPool) {
+	start := e.EncodeStart(10, 10)
+	e.EncodeString(r.Name)
+	e.EncodeString(r.NS)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWBucket(r *RGWBucket) {
+	start := e.EncodeStart(10, 10)
+	e.EncodeString(r.Name)
+	e.EncodeString(r.Marker)
+	e.EncodeString(r.BucketID)
+	e.EncodeString(r.Tenant)
+	encodeExplicit := r.ExplicitPlacement.DataPool.Name != ""
+	e.EncodeBool(encodeExplicit)
+	if encodeExplicit {
+		e.EncodeRGWPool(&r.ExplicitPlacement.DataPool)
+		e.EncodeRGWPool(&r.ExplicitPlacement.DataExtraPool)
+		e.EncodeRGWPool(&r.ExplicitPlacement.IndexPool)
+	}
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWObj(r *RGWObj) {
+	start := e.EncodeStart(6, 6)
+	e.EncodeRGWBucket(&r.Bucket)
+	e.EncodeString(r.Key.NS)
+	e.EncodeString(r.Key.Name)
+	e.EncodeString(r.Key.Instance)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWObjManifestRule(r *RGWObjManifestRule) {
+	start := e.EncodeStart(2, 1)
+	e.EncodeU32(r.StartPartNum)
+	e.EncodeU64(r.StartOfs)
+	e.EncodeU64(r.PartSize)
+	e.EncodeU64(r.StripeMaxSize)
+	e.EncodeString(r.OverridePrefix)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWObjManifestPart(r *RGWObjManifestPart) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeRGWObj(&r.Loc)
+	e.EncodeU64(r.LocOfs)
+	e.EncodeU64(r.Size)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWObjManifest(r *RGWObjManifest) {
+	start := e.EncodeStart(7, 6)
+	e.EncodeU64(r.ObjSize)
+	objsKeys := sortedPartKeys(r.Objs)
+	e.EncodeList(len(objsKeys), func(i int) {
+		part := r.Objs[objsKeys[i]]
+		e.EncodeU64(objsKeys[i])
+		e.EncodeRGWObjManifestPart(&part)
+	})
+	e.EncodeBool(r.ExplicitObjs)
+	e.EncodeRGWObj(&r.Obj)
+	e.EncodeU64(r.HeadSize)
+	e.EncodeU64(r.MaxHeapSize)
+	e.EncodeString(r.Prefix)
+	rulesKeys := r.Rules.keys()
+	e.EncodeList(len(rulesKeys), func(i int) {
+		rule := r.Rules[rulesKeys[i]]
+		e.EncodeU64(rulesKeys[i])
+		e.EncodeRGWObjManifestRule(&rule)
+	})
+	encodeTailBucket := r.TailPlacement.Bucket != r.Obj.Bucket
+	e.EncodeBool(encodeTailBucket)
+	if encodeTailBucket {
+		e.EncodeRGWBucket(&r.TailPlacement.Bucket)
+	}
+	encodeTailInstance := r.TailInstance != r.Obj.Key.Instance
+	e.EncodeBool(encodeTailInstance)
+	if encodeTailInstance {
+		e.EncodeString(r.TailInstance)
+	}
+	e.EncodeRGWPlacementRule(&r.HeadPlacementRule)
+	e.EncodeRGWPlacementRule(&r.TailPlacement.PlacementRule)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWObjManifest(r *RGWObjManifest) []byte {
+	e := NewEncoder()
+	e.EncodeRGWObjManifest(r)
+	return e.Bytes()
+}
+
+func EncodeRGWObj(r *RGWObj) []byte {
+	e := NewEncoder()
+	e.EncodeRGWObj(r)
+	return e.Bytes()
+}
+
+func EncodeRGWBucket(r *RGWBucket) []byte {
+	e := NewEncoder()
+	e.EncodeRGWBucket(r)
+	return e.Bytes()
+}
+
+func EncodeRGWPool(r *RGWPool) []byte {
+	e := NewEncoder()
+	e.EncodeRGWPool(r)
+	return e.Bytes()
+}
+
+func sortedPartKeys(m map[uint64]RGWObjManifestPart) []uint64 {
+	keys := make([]uint64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,330 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names of the xattrs RGW sets on the head object of an object.
+const (
+	RGWAttrPrefix       = "user.rgw."
+	RGWAttrACL          = RGWAttrPrefix + "acl"
+	RGWAttrETag         = RGWAttrPrefix + "etag"
+	RGWAttrContentType  = RGWAttrPrefix + "content_type"
+	RGWAttrManifest     = RGWAttrPrefix + "manifest"
+	RGWAttrTailTag      = RGWAttrPrefix + "tail_tag"
+	RGWAttrIDTag        = RGWAttrPrefix + "idtag"
+	RGWAttrPGVer        = RGWAttrPrefix + "pg_ver"
+	RGWAttrSourceZone   = RGWAttrPrefix + "source_zone"
+	RGWAttrStorageClass = RGWAttrPrefix + "storage_class"
+	RGWAttrTags         = RGWAttrPrefix + "x-amz-tagging"
+	RGWAttrCompression  = RGWAttrPrefix + "compression"
+	RGWAttrOLHInfo      = RGWAttrPrefix + "olh.info"
+	RGWAttrMetaPrefix   = RGWAttrPrefix + "x-amz-meta-"
+	RGWAttrCryptPrefix  = RGWAttrPrefix + "crypt."
+)
+
+// ObjectAttrs holds the decoded xattrs of a head object. UserMeta and Crypt
+// are keyed by the attr name without RGWAttrMetaPrefix and
+// RGWAttrCryptPrefix; attrs ObjectAttrs does not know are kept in Raw.
+type ObjectAttrs struct {
+	Manifest     *RGWObjManifest
+	ACL          *RGWAccessControlPolicy
+	ETag         string
+	ContentType  string
+	TailTag      string
+	IDTag        string
+	PGVer        uint64
+	SourceZone   uint32
+	StorageClass string
+	UserMeta     map[string]string
+	Tags         *RGWObjTags
+	Compression  *RGWCompressionInfo
+	Crypt        map[string]string
+	OLH          *RGWOLHInfo
+	Raw          map[string][]byte
+}
+
+// RGWObjTags is a RGWObjTags, the S3 tag set of an object.
+type RGWObjTags struct {
+	Tags map[string]string
+}
+
+// CompressionBlock is a compression_block, mapping a range of the original
+// data to its compressed location.
+type CompressionBlock struct {
+	OldOfs uint64
+	NewOfs uint64
+	Len    uint64
+}
+
+// RGWCompressionInfo is a RGWCompressionInfo.
+type RGWCompressionInfo struct {
+	CompressionType   string
+	OrigSize          uint64
+	CompressorMessage *int32
+	Blocks            []CompressionBlock
+}
+
+// RGWOLHInfo is a RGWOLHInfo, the instance the OLH of a versioned object
+// points to.
+type RGWOLHInfo struct {
+	Target  RGWObj
+	Removed bool
+}
+
+// DecodeObjectAttrs decodes the xattrs of a head object as returned by
+// `rados listxattr` and `rados getxattr`. The options apply to every
+// encoded attr; errors name the attr that failed.
+func DecodeObjectAttrs(attrs map[string][]byte, opts ...DecodeOptions) (*ObjectAttrs, error) {
+	r := ObjectAttrs{
+		UserMeta: make(map[string]string),
+		Crypt:    make(map[string]string),
+		Raw:      make(map[string][]byte),
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := r.decodeAttr(name, attrs[name], opts); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return &r, nil
+}
+
+func (r *ObjectAttrs) decodeAttr(name string, v []byte, opts []DecodeOptions) error {
+	d := NewDecoder(v, opts...)
+	var err error
+	switch name {
+	case RGWAttrManifest:
+		r.Manifest, err = d.DecodeRGWObjManifest()
+	case RGWAttrACL:
+		r.ACL, err = d.DecodeRGWAccessControlPolicy()
+	case RGWAttrETag:
+		r.ETag = attrString(v)
+		return nil
+	case RGWAttrContentType:
+		r.ContentType = attrString(v)
+		return nil
+	case RGWAttrTailTag:
+		r.TailTag = attrString(v)
+		return nil
+	case RGWAttrIDTag:
+		r.IDTag = attrString(v)
+		return nil
+	case RGWAttrStorageClass:
+		r.StorageClass = attrString(v)
+		return nil
+	case RGWAttrPGVer:
+		r.PGVer, err = d.DecodeU64()
+	case RGWAttrSourceZone:
+		r.SourceZone, err = d.DecodeU32()
+	case RGWAttrTags:
+		r.Tags, err = d.DecodeRGWObjTags()
+	case RGWAttrCompression:
+		r.Compression, err = d.DecodeRGWCompressionInfo()
+	case RGWAttrOLHInfo:
+		r.OLH, err = d.DecodeRGWOLHInfo()
+	default:
+		switch {
+		case strings.HasPrefix(name, RGWAttrMetaPrefix):
+			r.UserMeta[name[len(RGWAttrMetaPrefix):]] = attrString(v)
+		case strings.HasPrefix(name, RGWAttrCryptPrefix):
+			r.Crypt[name[len(RGWAttrCryptPrefix):]] = string(v)
+		default:
+			r.Raw[name] = v
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return d.Done()
+}
+
+// attrString returns a string attr without the NUL RGW appends to most of
+// them.
+func attrString(v []byte) string {
+	return strings.TrimRight(string(v), "\x00")
+}
+
+func DecodeRGWObjTags(data []byte, opts ...DecodeOptions) (*RGWObjTags, error) {
+	d := NewDecoder(data, opts...)
+	t, err := d.DecodeRGWObjTags()
+	if err != nil {
+		return nil, err
+	}
+	return t, d.Done()
+}
+
+func DecodeRGWCompressionInfo(data []byte, opts ...DecodeOptions) (*RGWCompressionInfo, error) {
+	d := NewDecoder(data, opts...)
+	c, err := d.DecodeRGWCompressionInfo()
+	if err != nil {
+		return nil, err
+	}
+	return c, d.Done()
+}
+
+func DecodeRGWOLHInfo(data []byte, opts ...DecodeOptions) (*RGWOLHInfo, error) {
+	d := NewDecoder(data, opts...)
+	o, err := d.DecodeRGWOLHInfo()
+	if err != nil {
+		return nil, err
+	}
+	return o, d.Done()
+}
+
+// DecodeRGWObjTags decodes a RGWObjTags.
+func (d *Decoder) DecodeRGWObjTags() (*RGWObjTags, error) {
+	var r RGWObjTags
+	_, _, structEnd, err := d.DecodeStart("RGWObjTags", 1)
+	if err != nil {
+		return nil, err
+	}
+	if r.Tags, err = d.DecodeStringMap("tag_map"); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeCompressionBlock decodes a compression_block.
+func (d *Decoder) DecodeCompressionBlock() (*CompressionBlock, error) {
+	var r CompressionBlock
+	_, _, structEnd, err := d.DecodeStart("compression_block", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("old_ofs")
+	if r.OldOfs, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("new_ofs")
+	if r.NewOfs, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	d.Field("len")
+	if r.Len, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWCompressionInfo decodes a RGWCompressionInfo.
+func (d *Decoder) DecodeRGWCompressionInfo() (*RGWCompressionInfo, error) {
+	var r RGWCompressionInfo
+	structV, _, structEnd, err := d.DecodeStart("RGWCompressionInfo", 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("compression_type")
+	if r.CompressionType, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("orig_size")
+	if r.OrigSize, err = d.DecodeU64(); err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("compressor_message")
+		_, err = d.DecodeOptional(func() error {
+			m, err := d.DecodeI32()
+			if err != nil {
+				return err
+			}
+			r.CompressorMessage = &m
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = d.DecodeList("blocks", func(i uint32) error {
+		b, err := d.DecodeCompressionBlock()
+		if err != nil {
+			return err
+		}
+		r.Blocks = append(r.Blocks, *b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWOLHInfo decodes a RGWOLHInfo.
+func (d *Decoder) DecodeRGWOLHInfo() (*RGWOLHInfo, error) {
+	var r RGWOLHInfo
+	_, _, structEnd, err := d.DecodeStart("RGWOLHInfo", 1)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("target")
+	target, err := d.DecodeRGWObj()
+	if err != nil {
+		return nil, err
+	}
+	r.Target = *target
+	d.Field("removed")
+	if r.Removed, err = d.DecodeBool(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeRGWObjTags(r *RGWObjTags) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeStringMap(r.Tags)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeCompressionBlock(r *CompressionBlock) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeU64(r.OldOfs)
+	e.EncodeU64(r.NewOfs)
+	e.EncodeU64(r.Len)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWCompressionInfo(r *RGWCompressionInfo) {
+	start := e.EncodeStart(2, 1)
+	e.EncodeString(r.CompressionType)
+	e.EncodeU64(r.OrigSize)
+	e.EncodeOptional(r.CompressorMessage != nil, func() {
+		e.EncodeI32(*r.CompressorMessage)
+	})
+	e.EncodeList(len(r.Blocks), func(i int) {
+		e.EncodeCompressionBlock(&r.Blocks[i])
+	})
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWOLHInfo(r *RGWOLHInfo) {
+	start := e.EncodeStart(1, 1)
+	e.EncodeRGWObj(&r.Target)
+	e.EncodeBool(r.Removed)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWObjTags(r *RGWObjTags) []byte {
+	e := NewEncoder()
+	e.EncodeRGWObjTags(r)
+	return e.Bytes()
+}
+
+func EncodeRGWCompressionInfo(r *RGWCompressionInfo) []byte {
+	e := NewEncoder()
+	e.EncodeRGWCompressionInfo(r)
+	return e.Bytes()
+}
+
+func EncodeRGWOLHInfo(r *RGWOLHInfo) []byte {
+	e := NewEncoder()
+	e.EncodeRGWOLHInfo(r)
+	return e.Bytes()
+}
@@ -0,0 +1,126 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeObjectAttrs(t *testing.T) {
+	manifest, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	pgVer := NewEncoder()
+	pgVer.EncodeU64(1234)
+	sourceZone := NewEncoder()
+	sourceZone.EncodeU32(7)
+	tags := &RGWObjTags{Tags: map[string]string{"project": "x", "team": "storage"}}
+	compression := &RGWCompressionInfo{
+		CompressionType: "zstd",
+		OrigSize:        8 << 20,
+		Blocks: []CompressionBlock{
+			{OldOfs: 0, NewOfs: 0, Len: 1000},
+			{OldOfs: 4 << 20, NewOfs: 1000, Len: 2000},
+		},
+	}
+	acl := &RGWAccessControlPolicy{
+		ACL: RGWAccessControlList{
+			UserMap:  map[string]int32{"user": PermFullControl},
+			GroupMap: map[ACLGroupType]int32{},
+			GrantMap: []ACLGrantEntry{{Key: "user", Grant: ACLGrant{ID: RGWUser{ID: "user"}, Permission: PermFullControl}}},
+		},
+		Owner: ACLOwner{ID: RGWUser{ID: "user"}, DisplayName: "User"},
+	}
+	olh := &RGWOLHInfo{Target: RGWObj{
+		Bucket: RGWBucket{Name: "bucket", Marker: "zone.4217.1", BucketID: "zone.4217.1"},
+		Key:    RGWObjKey{Name: "cat.jpg", Instance: "XgfYa"},
+	}}
+
+	attrs, err := DecodeObjectAttrs(map[string][]byte{
+		RGWAttrManifest:                     manifest,
+		RGWAttrACL:                          EncodeRGWAccessControlPolicy(acl),
+		RGWAttrETag:                         []byte("d41d8cd98f00b204e9800998ecf8427e\x00"),
+		RGWAttrContentType:                  []byte("image/jpeg\x00"),
+		RGWAttrTailTag:                      []byte("zone.4217.123\x00"),
+		RGWAttrIDTag:                        []byte("zone.4217.123\x00"),
+		RGWAttrPGVer:                        pgVer.Bytes(),
+		RGWAttrSourceZone:                   sourceZone.Bytes(),
+		RGWAttrStorageClass:                 []byte("COLD"),
+		RGWAttrMetaPrefix + "color":         []byte("orange\x00"),
+		RGWAttrTags:                         EncodeRGWObjTags(tags),
+		RGWAttrCompression:                  EncodeRGWCompressionInfo(compression),
+		RGWAttrCryptPrefix + "mode":         []byte("SSE-C-AES256"),
+		RGWAttrOLHInfo:                      EncodeRGWOLHInfo(olh),
+		RGWAttrPrefix + "olh.pending.00001": []byte("x"),
+		"ceph.objclass.version":             {1},
+	}, DecodeOptions{ErrorOnLeftover: true})
+	assert.NoError(t, err)
+
+	expected, err := DecodeRGWObjManifest(manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, attrs.Manifest)
+	assert.Equal(t, acl, attrs.ACL)
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", attrs.ETag)
+	assert.Equal(t, "image/jpeg", attrs.ContentType)
+	assert.Equal(t, "zone.4217.123", attrs.TailTag)
+	assert.Equal(t, "zone.4217.123", attrs.IDTag)
+	assert.Equal(t, uint64(1234), attrs.PGVer)
+	assert.Equal(t, uint32(7), attrs.SourceZone)
+	assert.Equal(t, "COLD", attrs.StorageClass)
+	assert.Equal(t, map[string]string{"color": "orange"}, attrs.UserMeta)
+	assert.Equal(t, tags, attrs.Tags)
+	assert.Equal(t, compression, attrs.Compression)
+	assert.Equal(t, map[string]string{"mode": "SSE-C-AES256"}, attrs.Crypt)
+	assert.Equal(t, olh, attrs.OLH)
+	assert.Equal(t, map[string][]byte{
+		RGWAttrPrefix + "olh.pending.00001": []byte("x"),
+		"ceph.objclass.version":             {1},
+	}, attrs.Raw)
+
+	_, err = DecodeObjectAttrs(map[string][]byte{RGWAttrPGVer: {1, 2}})
+	assert.True(t, errors.Is(err, ErrTruncated))
+	assert.Contains(t, err.Error(), RGWAttrPGVer)
+}
+
+func TestEncodeDecodeRGWCompressionInfo(t *testing.T) {
+	msg := int32(-15)
+	expected := &RGWCompressionInfo{
+		CompressionType:   "zlib",
+		OrigSize:          4096,
+		CompressorMessage: &msg,
+		Blocks:            []CompressionBlock{{Len: 100}},
+	}
+	data := EncodeRGWCompressionInfo(expected)
+	actual, err := DecodeRGWCompressionInfo(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	out, err := json.Marshal(actual)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"compression_type": "zlib",
+		"orig_size": 4096,
+		"compressor_message": -15,
+		"blocks": [{"old_ofs": 0, "new_ofs": 0, "len": 100}]
+	}`, string(out))
+
+	// v1 has no compressor_message.
+	e := NewEncoder()
+	start := e.EncodeStart(1, 1)
+	e.EncodeString("snappy")
+	e.EncodeU64(10)
+	e.EncodeU32(0)
+	e.EncodeFinish(start)
+	actual, err = DecodeRGWCompressionInfo(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, &RGWCompressionInfo{CompressionType: "snappy", OrigSize: 10}, actual)
+}
+
+func TestRGWObjTagsJSON(t *testing.T) {
+	out, err := json.Marshal(&RGWObjTags{Tags: map[string]string{"b": "2", "a": "1"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"tagset": {"a": "1", "b": "2"}}`, string(out))
+}
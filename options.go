@@ -0,0 +1,116 @@
+package decoder
+
+// Mode selects how forgiving the decoders are with unexpected input.
+type Mode int
+
+const (
+	// ModeDefault decodes like Ceph does: fields appended by newer struct
+	// versions are skipped, anything else unexpected fails.
+	ModeDefault Mode = iota
+	// ModeStrict also rejects struct versions newer than the decoder knows
+	// and unknown trailing struct bytes, so everything in the input is
+	// accounted for.
+	ModeStrict
+	// ModeBestEffort keeps going on legacy data that Ceph would reject but
+	// that can still be interpreted, e.g. a non-empty legacy bucket name in
+	// a cls_user_bucket_entry.
+	ModeBestEffort
+)
+
+// DecodeOptions control a decode. The zero value decodes like Ceph and
+// ignores bytes after the top-level value.
+type DecodeOptions struct {
+	Mode Mode
+	// ErrorOnLeftover makes a decode fail with ErrLeftover if bytes follow
+	// the top-level value.
+	ErrorOnLeftover bool
+	// MaxVersions overrides, by Ceph type name, the highest struct version
+	// the decoder of the type accepts. Raising it decodes encodings whose
+	// compat version is newer as far as their fields are known, lowering it
+	// rejects newer encodings.
+	MaxVersions map[string]uint8
+	// Report, if set, receives the details of a successful decode.
+	Report *DecodeReport
+	// Explain, if set, collects the offset and bytes of every field read.
+	Explain *Explanation
+	// Strings, if set, interns the decoded strings.
+	Strings *StringTable
+	// Logger, if set, logs the warnings of the decode.
+	Logger Logger
+}
+
+// StringTable interns decoded strings, so values repeated across many
+// records, like bucket markers and pool names, are allocated once. It may be
+// shared by decoders running one after another, e.g. through
+// Reader.Options, but not by concurrent ones.
+type StringTable struct {
+	max     int
+	strings map[string]string
+}
+
+// NewStringTable returns a StringTable holding up to max strings. Once it is
+// full, strings not in the table are allocated as usual.
+func NewStringTable(max int) *StringTable {
+	return &StringTable{
+		max:     max,
+		strings: make(map[string]string),
+	}
+}
+
+func (t *StringTable) intern(b []byte) string {
+	if s, ok := t.strings[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(t.strings) < t.max {
+		t.strings[s] = s
+	}
+	return s
+}
+
+// DecodeReport describes the parts of the input a decode did not interpret.
+type DecodeReport struct {
+	// Leftover holds the bytes following the top-level value.
+	Leftover []byte
+	// Skipped is the number of unknown trailing struct bytes, which are
+	// listed in Unknown.
+	Skipped uint32
+	Unknown []UnknownBytes
+	// Warnings lists the values that were defaulted or normalized.
+	Warnings []Warning
+}
+
+func (d *Decoder) maxVersion(typ string, v uint32) uint32 {
+	if max, ok := d.opts.MaxVersions[typ]; ok {
+		return uint32(max)
+	}
+	return v
+}
+
+// Done ends the decode of a top-level value. It applies the leftover policy
+// of the options and fills their Report.
+func (d *Decoder) Done() error {
+	var leftover []byte
+	if d.Remaining() > 0 {
+		leftover = d.Data[d.Offset:]
+		if d.opts.ErrorOnLeftover {
+			return d.NewError(ErrLeftover)
+		}
+		if d.explaining() {
+			d.addSpan(Span{
+				Offset:  d.Offset,
+				Raw:     leftover,
+				Unknown: true,
+			})
+		}
+	}
+	if r := d.opts.Report; r != nil {
+		*r = DecodeReport{
+			Leftover: leftover,
+			Skipped:  d.skipped,
+			Unknown:  d.unknown,
+			Warnings: d.warnings,
+		}
+	}
+	return nil
+}
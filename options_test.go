@@ -0,0 +1,167 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeOptionsMode(t *testing.T) {
+	testcases := []struct {
+		name    string
+		file    string
+		corrupt func([]byte)
+		decode  func([]byte, DecodeOptions) error
+		modes   map[Mode]error
+	}{
+		{
+			name: "newer version",
+			file: "testdata/manifest_1",
+			corrupt: func(data []byte) {
+				data[0] = 8
+			},
+			decode: func(data []byte, opts DecodeOptions) error {
+				_, err := DecodeRGWObjManifest(data, opts)
+				return err
+			},
+			modes: map[Mode]error{
+				ModeDefault:    nil,
+				ModeStrict:     ErrNewVersion,
+				ModeBestEffort: nil,
+			},
+		},
+		{
+			name: "unknown struct bytes",
+			file: "testdata/bucket_entry_1",
+			corrupt: func(data []byte) {
+				// Grow the struct by one byte, the legacy empty string
+				// length happens to be zero.
+				data[2]++
+			},
+			decode: func(data []byte, opts DecodeOptions) error {
+				_, err := DecodeUserBucketEntry(append(data, 0), opts)
+				return err
+			},
+			modes: map[Mode]error{
+				ModeDefault:    nil,
+				ModeStrict:     ErrLeftover,
+				ModeBestEffort: nil,
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			assert.NoError(t, err)
+			tt.corrupt(data)
+
+			for mode, target := range tt.modes {
+				err := tt.decode(data, DecodeOptions{Mode: mode})
+				if target == nil {
+					assert.NoError(t, err, "mode %d", mode)
+				} else {
+					assert.True(t, errors.Is(err, target), "mode %d: %v", mode, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeOptionsLegacyBucketName(t *testing.T) {
+	expected := &UserBucketEntry{
+		Size:  1,
+		Count: 2,
+		Bucket: UserBucket{
+			Name:        "bucket",
+			Marker:      "zone.1234.1",
+			BucketID:    "zone.1234.1",
+			PlacementID: "default-placement",
+		},
+		SizeRounded: 4096,
+	}
+	e := NewEncoder()
+	start := e.EncodeStart(9, 5)
+	e.EncodeString("bucket")
+	e.EncodeU64(expected.Size)
+	e.EncodeU32(0)
+	e.EncodeU64(expected.Count)
+	e.EncodeUserBucket(&expected.Bucket)
+	e.EncodeU64(expected.SizeRounded)
+	e.EncodeBool(false)
+	e.EncodeRealTime(expected.CreationTime)
+	e.EncodeFinish(start)
+
+	_, err := DecodeUserBucketEntry(e.Bytes())
+	assert.True(t, errors.Is(err, ErrIncompatible))
+	_, err = DecodeUserBucketEntry(e.Bytes(), DecodeOptions{Mode: ModeStrict})
+	assert.True(t, errors.Is(err, ErrIncompatible))
+	u, err := DecodeUserBucketEntry(e.Bytes(), DecodeOptions{Mode: ModeBestEffort})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, u)
+}
+
+func TestDecodeOptionsLeftover(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	data = append(data, 1, 2, 3)
+
+	_, err = DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+
+	var report DecodeReport
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{Report: &report})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, report.Leftover)
+
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{ErrorOnLeftover: true})
+	assert.True(t, errors.Is(err, ErrLeftover))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, uint32(len(data)-3), de.Offset)
+
+	_, err = Decode("RGWObjManifest", data, DecodeOptions{ErrorOnLeftover: true})
+	assert.True(t, errors.Is(err, ErrLeftover))
+}
+
+func TestDecodeOptionsMaxVersions(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{
+		MaxVersions: map[string]uint8{"RGWObjManifest": 5},
+	})
+	assert.True(t, errors.Is(err, ErrOldVersion))
+
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{
+		Mode:        ModeStrict,
+		MaxVersions: map[string]uint8{"RGWObjManifest": 6},
+	})
+	assert.True(t, errors.Is(err, ErrNewVersion))
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, uint8(7), de.Version)
+	assert.Equal(t, uint8(6), de.Compat)
+
+	// The compat version is checked before the version.
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{
+		Mode:        ModeStrict,
+		MaxVersions: map[string]uint8{"rgw_bucket": 9},
+	})
+	assert.True(t, errors.Is(err, ErrOldVersion))
+
+	// A compat version above what the decoder knows.
+	data[1] = 8
+	_, err = DecodeRGWObjManifest(data)
+	assert.True(t, errors.Is(err, ErrOldVersion))
+	m, err := DecodeRGWObjManifest(data, DecodeOptions{
+		MaxVersions: map[string]uint8{"RGWObjManifest": 8},
+	})
+	assert.NoError(t, err)
+	data[1] = 6
+	expected, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Obj, m.Obj)
+}
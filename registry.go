@@ -0,0 +1,173 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DecodeFunc decodes a value of a registered type.
+type DecodeFunc func(d *Decoder) (interface{}, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]DecodeFunc)
+)
+
+func init() {
+	Register("cls_user_bucket", func(d *Decoder) (interface{}, error) {
+		return d.DecodeUserBucket()
+	})
+	Register("cls_user_bucket_entry", func(d *Decoder) (interface{}, error) {
+		return d.DecodeUserBucketEntry()
+	})
+	Register("RGWObjManifest", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObjManifest()
+	})
+	Register("RGWObjManifestPart", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObjManifestPart()
+	})
+	Register("RGWObjManifestRule", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObjManifestRule()
+	})
+	Register("rgw_obj", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObj()
+	})
+	Register("rgw_obj_key", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObjKey()
+	})
+	Register("rgw_bucket", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucket()
+	})
+	Register("rgw_pool", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWPool()
+	})
+	Register("RGWBucketInfo", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketInfo()
+	})
+	Register("RGWQuotaInfo", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWQuotaInfo()
+	})
+	Register("RGWBucketEntryPoint", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketEntryPoint()
+	})
+	Register("obj_version", func(d *Decoder) (interface{}, error) {
+		return d.DecodeObjVersion()
+	})
+	Register("RGWUserInfo", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWUserInfo()
+	})
+	Register("RGWAccessKey", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWAccessKey()
+	})
+	Register("RGWSubUser", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWSubUser()
+	})
+	Register("RGWUID", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWUID()
+	})
+	Register(UserUIDNS, func(d *Decoder) (interface{}, error) {
+		return d.DecodeUserObject()
+	})
+	Register("rgw_bucket_dir_entry", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketDirEntry()
+	})
+	Register("rgw_bucket_dir_entry_meta", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketDirEntryMeta()
+	})
+	Register("rgw_bucket_pending_info", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketPendingInfo()
+	})
+	Register("rgw_bucket_entry_ver", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketEntryVer()
+	})
+	Register("rgw_bucket_dir_header", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketDirHeader()
+	})
+	Register("rgw_bucket_category_stats", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketCategoryStats()
+	})
+	Register("cls_rgw_obj_key", func(d *Decoder) (interface{}, error) {
+		return d.DecodeClsRGWObjKey()
+	})
+	Register("rgw_bucket_olh_entry", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketOLHEntry()
+	})
+	Register("rgw_bucket_olh_log_entry", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBucketOLHLogEntry()
+	})
+	Register("rgw_bi_log_entry", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWBILogEntry()
+	})
+	Register("RGWObjTags", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWObjTags()
+	})
+	Register("RGWCompressionInfo", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWCompressionInfo()
+	})
+	Register("compression_block", func(d *Decoder) (interface{}, error) {
+		return d.DecodeCompressionBlock()
+	})
+	Register("RGWOLHInfo", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWOLHInfo()
+	})
+	Register("RGWAccessControlPolicy", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWAccessControlPolicy()
+	})
+	Register("RGWAccessControlList", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWAccessControlList()
+	})
+	Register("ACLOwner", func(d *Decoder) (interface{}, error) {
+		return d.DecodeACLOwner()
+	})
+	Register("ACLGrant", func(d *Decoder) (interface{}, error) {
+		return d.DecodeACLGrant()
+	})
+	Register("rgw_user", func(d *Decoder) (interface{}, error) {
+		return d.DecodeRGWUser()
+	})
+}
+
+// Register makes a decoder available to Decode under the Ceph type name
+// typeName. It panics if the name is already registered.
+func Register(typeName string, fn DecodeFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if fn == nil {
+		panic("decoder: Register decode func is nil")
+	}
+	if _, dup := registry[typeName]; dup {
+		panic("decoder: Register called twice for type " + typeName)
+	}
+	registry[typeName] = fn
+}
+
+// Decode decodes data as the Ceph type typeName, like
+// `ceph-dencoder type <typeName> import <file> decode`.
+func Decode(typeName string, data []byte, opts ...DecodeOptions) (interface{}, error) {
+	registryMu.RLock()
+	fn, ok := registry[typeName]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, typeName)
+	}
+	d := NewDecoder(data, opts...)
+	v, err := fn(d)
+	if err != nil {
+		return nil, err
+	}
+	return v, d.Done()
+}
+
+// ListTypes returns the sorted names of all registered types, like
+// `ceph-dencoder list_types`.
+func ListTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for name := range registry {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
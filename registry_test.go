@@ -0,0 +1,59 @@
+package decoder
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRegistered(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	v, err := Decode("RGWObjManifest", data)
+	assert.NoError(t, err)
+	expected, err := DecodeRGWObjManifest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Obj, v.(*RGWObjManifest).Obj)
+
+	data, err = ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	v, err = Decode("cls_user_bucket_entry", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "gzim", v.(*UserBucketEntry).Bucket.Name)
+
+	_, err = Decode("no_such_type", data)
+	assert.True(t, errors.Is(err, ErrUnknownType))
+}
+
+func TestRegister(t *testing.T) {
+	Register("test_u32", func(d *Decoder) (interface{}, error) {
+		return d.DecodeU32()
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test_u32")
+		registryMu.Unlock()
+	}()
+
+	assert.Contains(t, ListTypes(), "test_u32")
+	v, err := Decode("test_u32", []byte{1, 0, 0, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), v)
+
+	assert.Panics(t, func() {
+		Register("test_u32", func(d *Decoder) (interface{}, error) {
+			return nil, nil
+		})
+	})
+}
+
+func TestListTypes(t *testing.T) {
+	types := ListTypes()
+	assert.Contains(t, types, "RGWObjManifest")
+	assert.Contains(t, types, "cls_user_bucket")
+	assert.Contains(t, types, "cls_user_bucket_entry")
+	assert.True(t, sort.StringsAreSorted(types))
+}
@@ -0,0 +1,117 @@
+package decoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxRecordSize is the largest record a Reader accepts unless
+// MaxRecordSize says otherwise.
+const DefaultMaxRecordSize = 64 << 20
+
+// Framing describes the struct header a record starts with, in the terms of
+// DecodeStartLegacyCompatLen: encodings older than CompatV carry no compat
+// version and encodings older than LenV carry no length. The zero Framing
+// is a plain DECODE_START header.
+type Framing struct {
+	CompatV uint8
+	LenV    uint8
+}
+
+// Framings of the registered top-level types.
+var (
+	FramingUserBucket      = Framing{CompatV: 3, LenV: 3}
+	FramingUserBucketEntry = Framing{CompatV: 5, LenV: 5}
+	FramingRGWObjManifest  = Framing{CompatV: 2, LenV: 2}
+)
+
+// Reader splits a stream of concatenated encoded records, e.g. a dump of
+// many RGWObjManifests, into single records. Only the current record is held
+// in memory, so streams may be of any size.
+type Reader struct {
+	// MaxRecordSize bounds the size of a record including its header.
+	// Larger records fail with ErrPastEnd instead of being buffered.
+	MaxRecordSize uint32
+	// Options are passed to the Decoder of every record.
+	Options DecodeOptions
+
+	r       *bufio.Reader
+	framing Framing
+	offset  uint64
+	next    uint64
+	buf     []byte
+	d       Decoder
+}
+
+// NewReader returns a Reader reading records framed as f from r.
+func NewReader(r io.Reader, f Framing) *Reader {
+	return &Reader{
+		MaxRecordSize: DefaultMaxRecordSize,
+		r:             bufio.NewReader(r),
+		framing:       f,
+	}
+}
+
+// Next reads the next record and returns a Decoder positioned at its start.
+// The Decoder is reused and only valid until the following call to Next. At the
+// end of the stream Next returns io.EOF; a stream ending within a record
+// fails with ErrTruncated. Errors are returned as a RecordError.
+func (r *Reader) Next() (*Decoder, error) {
+	r.offset = r.next
+	// The header is at most u8 version, u8 compat and u32 length.
+	header, err := r.r.Peek(6)
+	if len(header) == 0 {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, r.newError(err)
+	}
+	d := NewDecoder(header)
+	_, _, structLen, hasLen, err := d.decodeHeader(255, uint32(r.framing.CompatV), uint32(r.framing.LenV), 0)
+	if err != nil {
+		return nil, r.newError(err)
+	}
+	if !hasLen {
+		return nil, r.newError(fmt.Errorf("%w: record header carries no length", ErrIncompatible))
+	}
+	if structLen > r.MaxRecordSize || d.Offset+structLen > r.MaxRecordSize {
+		return nil, r.newError(ErrPastEnd)
+	}
+	size := d.Offset + structLen
+	if uint32(cap(r.buf)) < size {
+		r.buf = make([]byte, size)
+	}
+	r.buf = r.buf[:size]
+	n, err := io.ReadFull(r.r, r.buf)
+	r.next += uint64(n)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = &TruncatedError{
+				Offset:    uint32(n),
+				Need:      size - uint32(n),
+				Remaining: 0,
+			}
+		}
+		return nil, r.newError(err)
+	}
+	r.d = Decoder{
+		Data: r.buf,
+		opts: r.Options,
+	}
+	r.d.frames = r.d.frameBuf[:0]
+	return &r.d, nil
+}
+
+// Offset returns the position in the stream of the record last returned by
+// Next.
+func (r *Reader) Offset() uint64 {
+	return r.offset
+}
+
+func (r *Reader) newError(err error) error {
+	return &RecordError{
+		Offset: r.offset,
+		Err:    err,
+	}
+}
@@ -0,0 +1,114 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	var stream []byte
+	var offsets []uint64
+	var expected []*RGWObjManifest
+	for _, file := range []string{"testdata/manifest_1", "testdata/manifest_2", "testdata/manifest_3"} {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		m, err := DecodeRGWObjManifest(data)
+		assert.NoError(t, err)
+		offsets = append(offsets, uint64(len(stream)))
+		expected = append(expected, m)
+		stream = append(stream, data...)
+	}
+
+	r := NewReader(bytes.NewReader(stream), FramingRGWObjManifest)
+	for i := range expected {
+		d, err := r.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, offsets[i], r.Offset())
+		m, err := d.DecodeRGWObjManifest()
+		assert.NoError(t, err)
+		assert.Equal(t, expected[i].Obj, m.Obj)
+		assert.Equal(t, expected[i].Rules, m.Rules)
+		assert.Zero(t, d.Remaining())
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderDecodeStart(t *testing.T) {
+	rules := []RGWObjManifestRule{
+		{StartPartNum: 1, StripeMaxSize: 4 << 20},
+		{StartPartNum: 2, StripeMaxSize: 4 << 20, OverridePrefix: "prefix"},
+	}
+	e := NewEncoder()
+	for i := range rules {
+		e.EncodeRGWObjManifestRule(&rules[i])
+	}
+
+	r := NewReader(bytes.NewReader(e.Bytes()), Framing{})
+	for i := range rules {
+		d, err := r.Next()
+		assert.NoError(t, err)
+		rule, err := d.DecodeRGWObjManifestRule()
+		assert.NoError(t, err)
+		assert.Equal(t, rules[i], *rule)
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderErrors(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+
+	testcases := []struct {
+		name    string
+		stream  []byte
+		framing Framing
+		max     uint32
+		target  error
+	}{
+		{
+			name:    "truncated record",
+			stream:  append(append([]byte{}, data...), data[:len(data)-1]...),
+			framing: FramingRGWObjManifest,
+			target:  ErrTruncated,
+		},
+		{
+			name:    "truncated header",
+			stream:  append(append([]byte{}, data...), data[:3]...),
+			framing: FramingRGWObjManifest,
+			target:  ErrTruncated,
+		},
+		{
+			name:    "record too large",
+			stream:  append(append([]byte{}, data...), 7, 6, 0xff, 0xff, 0xff, 0xff),
+			framing: FramingRGWObjManifest,
+			target:  ErrPastEnd,
+		},
+		{
+			name:    "no length",
+			stream:  append(append([]byte{}, data...), 1, 0, 0, 0, 0, 0),
+			framing: FramingRGWObjManifest,
+			target:  ErrIncompatible,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(tt.stream), tt.framing)
+			_, err := r.Next()
+			assert.NoError(t, err)
+
+			_, err = r.Next()
+			assert.True(t, errors.Is(err, tt.target), "%v", err)
+			var re *RecordError
+			assert.True(t, errors.As(err, &re))
+			assert.Equal(t, uint64(len(data)), re.Offset)
+		})
+	}
+}
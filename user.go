@@ -1,9 +1,8 @@
 package decoder
 
 import (
-	"errors"
 	"fmt"
-	"log"
+	"time"
 )
 
 type UserBucket struct {
@@ -20,52 +19,62 @@ type explicitPlacement struct {
 	DataExtraPool string
 }
 
-func DecodeUserBucket(data []byte) (*UserBucket, error) {
-	d := &decoder{
-		Data: data,
+func DecodeUserBucket(data []byte, opts ...DecodeOptions) (*UserBucket, error) {
+	d := NewDecoder(data, opts...)
+	u, err := d.DecodeUserBucket()
+	if err != nil {
+		return nil, err
 	}
-	return d.decodeUserBucket()
+	return u, d.Done()
 }
 
-func DecodeUserBucketEntry(data []byte) (*UserBucketEntry, error) {
-	d := &decoder{
-		Data: data,
+func DecodeUserBucketEntry(data []byte, opts ...DecodeOptions) (*UserBucketEntry, error) {
+	d := NewDecoder(data, opts...)
+	u, err := d.DecodeUserBucketEntry()
+	if err != nil {
+		return nil, err
 	}
-	return d.decodeUserBucketEntry()
+	return u, d.Done()
 }
 
-func (d *decoder) decodeUserBucket() (*UserBucket, error) {
+// DecodeUserBucket decodes a cls_user_bucket.
+func (d *Decoder) DecodeUserBucket() (*UserBucket, error) {
 	var u UserBucket
-	structV, structEnd, err := d.decodeStartLegacyCompatLen(8, 3, 3)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("cls_user_bucket", 9, 3, 3)
 	if err != nil {
 		return nil, err
 	}
-	name, err := d.decodeString()
+	d.Field("name")
+	name, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
 	u.Name = name
 	if structV < 8 {
-		p, err := d.decodeString()
+		d.Field("explicit_placement.data_pool")
+		p, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		u.ExplicitPlacement.DataPool = p
 	}
 	if structV >= 2 {
-		marker, err := d.decodeString()
+		d.Field("marker")
+		marker, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
 		u.Marker = marker
 		if structV <= 3 {
-			id, err := d.decodeU64()
+			d.Field("bucket_id")
+			id, err := d.DecodeU64()
 			if err != nil {
 				return nil, err
 			}
 			u.BucketID = fmt.Sprintf("%d", id)
 		} else {
-			id, err := d.decodeString()
+			d.Field("bucket_id")
+			id, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
@@ -75,29 +84,45 @@ func (d *decoder) decodeUserBucket() (*UserBucket, error) {
 	}
 	if structV < 8 {
 		if structV >= 5 {
-			p, err := d.decodeString()
+			d.Field("explicit_placement.index_pool")
+			p, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
 			u.ExplicitPlacement.IndexPool = p
 		} else {
 			u.ExplicitPlacement.IndexPool = u.ExplicitPlacement.DataPool
+			d.Field("explicit_placement.index_pool")
+			d.Warn(WarnDefaultedIndexPool, "struct_v %d has no index pool, using data pool %q", structV, u.ExplicitPlacement.DataPool)
+		}
+		if structV >= 7 {
+			d.Field("explicit_placement.data_extra_pool")
+			p, err := d.DecodeString()
+			if err != nil {
+				return nil, err
+			}
+			u.ExplicitPlacement.DataExtraPool = p
 		}
 	} else {
-		pid, err := d.decodeString()
+		d.Field("placement_id")
+		pid, err := d.DecodeString()
 		if err != nil {
 			return nil, err
 		}
-		if structV == 8 && pid != "" {
-			dp, err := d.decodeString()
+		u.PlacementID = pid
+		if structV == 8 && pid == "" {
+			d.Field("explicit_placement.data_pool")
+			dp, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
-			ip, err := d.decodeString()
+			d.Field("explicit_placement.index_pool")
+			ip, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
-			dep, err := d.decodeString()
+			d.Field("explicit_placement.data_extra_pool")
+			dep, err := d.DecodeString()
 			if err != nil {
 				return nil, err
 			}
@@ -106,68 +131,146 @@ func (d *decoder) decodeUserBucket() (*UserBucket, error) {
 			u.ExplicitPlacement.DataExtraPool = dep
 		}
 	}
-	if structEnd > 0 {
-		if d.Offset > structEnd {
-			return nil, errors.New("DECODE_ERR_PAST")
-		}
-	}
-	return &u, nil
+	return &u, d.DecodeFinish(structEnd)
 }
 
 type UserBucketEntry struct {
 	Size           uint64
 	SizeRounded    uint64
+	CreationTime   time.Time
 	Count          uint64
 	UserStatusSync bool
 	Bucket         UserBucket
 }
 
-func (d *decoder) decodeUserBucketEntry() (*UserBucketEntry, error) {
+// DecodeUserBucketEntry decodes a cls_user_bucket_entry.
+func (d *Decoder) DecodeUserBucketEntry() (*UserBucketEntry, error) {
 	var u UserBucketEntry
-	structV, structEnd, err := d.decodeStartLegacyCompatLen(9, 5, 5)
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen("cls_user_bucket_entry", 9, 5, 5)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("empty_str")
+	s, err := d.DecodeString()
 	if err != nil {
 		return nil, err
 	}
-	s, err := d.decodeString()
-	if err != nil || s != "" {
-		return nil, fmt.Errorf("notcompat or :%v", err)
+	if s != "" {
+		if d.opts.Mode != ModeBestEffort {
+			return nil, d.NewError(ErrIncompatible)
+		}
+		d.Warn(WarnLegacyBucketName, "ignored legacy bucket name %q", s)
 	}
-	size, err := d.decodeU64()
+	d.Field("size")
+	size, err := d.DecodeU64()
 	if err != nil {
 		return nil, err
 	}
 	u.SizeRounded = size
 	u.Size = size
-	if _, err = d.decodeU32(); err != nil {
+	d.Field("creation_time")
+	mt, err := d.DecodeU32()
+	if err != nil {
 		return nil, err
 	}
 	if structV < 7 {
-		log.Println("version low")
+		d.Warn(WarnLegacyCreationTime, "struct_v %d stores the creation time in seconds", structV)
+		u.CreationTime = timeFromParts(mt, 0)
 	}
 	if structV >= 2 {
-		count, err := d.decodeU64()
+		d.Field("count")
+		count, err := d.DecodeU64()
 		if err != nil {
 			return nil, err
 		}
 		u.Count = count
 	}
 	if structV >= 3 {
-		bucket, err := d.decodeUserBucket()
+		d.Field("bucket")
+		bucket, err := d.DecodeUserBucket()
 		if err != nil {
 			return nil, err
 		}
 		u.Bucket = *bucket
 	}
 	if structV >= 4 {
-		size, err := d.decodeU64()
+		d.Field("size_rounded")
+		size, err := d.DecodeU64()
 		if err != nil {
 			return nil, err
 		}
 		u.SizeRounded = size
 	}
 	if structV >= 6 {
-		userst := d.decodeU8()
-		u.UserStatusSync = userst != 0
+		d.Field("user_stats_sync")
+		userst, err := d.DecodeBool()
+		if err != nil {
+			return nil, err
+		}
+		u.UserStatusSync = userst
+	}
+	if structV >= 7 {
+		d.Field("creation_time")
+		ct, err := d.DecodeRealTime()
+		if err != nil {
+			return nil, err
+		}
+		u.CreationTime = ct
 	}
-	return &u, d.decodeFinish(structEnd)
+	if structV == 8 {
+		d.Field("placement_rule")
+		if _, err := d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	return &u, d.DecodeFinish(structEnd)
+}
+
+// EncodeUserBucket encodes a cls_user_bucket the way Ceph does: buckets with
+// a placement id use the current encoding, all others the v7 one that older
+// OSDs still understand.
+func (e *Encoder) EncodeUserBucket(u *UserBucket) {
+	if u.PlacementID != "" {
+		start := e.EncodeStart(9, 8)
+		e.EncodeString(u.Name)
+		e.EncodeString(u.Marker)
+		e.EncodeString(u.BucketID)
+		e.EncodeString(u.PlacementID)
+		e.EncodeFinish(start)
+		return
+	}
+	start := e.EncodeStart(7, 3)
+	e.EncodeString(u.Name)
+	e.EncodeString(u.ExplicitPlacement.DataPool)
+	e.EncodeString(u.Marker)
+	e.EncodeString(u.BucketID)
+	e.EncodeString(u.ExplicitPlacement.IndexPool)
+	e.EncodeString(u.ExplicitPlacement.DataExtraPool)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeUserBucketEntry(u *UserBucketEntry) {
+	start := e.EncodeStart(9, 5)
+	mt, _ := timeParts(u.CreationTime)
+	e.EncodeString("")
+	e.EncodeU64(u.Size)
+	e.EncodeU32(mt)
+	e.EncodeU64(u.Count)
+	e.EncodeUserBucket(&u.Bucket)
+	e.EncodeU64(u.SizeRounded)
+	e.EncodeBool(u.UserStatusSync)
+	e.EncodeRealTime(u.CreationTime)
+	e.EncodeFinish(start)
+}
+
+func EncodeUserBucket(u *UserBucket) []byte {
+	e := NewEncoder()
+	e.EncodeUserBucket(u)
+	return e.Bytes()
+}
+
+func EncodeUserBucketEntry(u *UserBucketEntry) []byte {
+	e := NewEncoder()
+	e.EncodeUserBucketEntry(u)
+	return e.Bytes()
 }
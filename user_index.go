@@ -0,0 +1,134 @@
+package decoder
+
+import "strings"
+
+// Namespaces of the user objects in the zone's meta pool.
+const (
+	UserUIDNS   = "users.uid"
+	UserKeysNS  = "users.keys"
+	UserEmailNS = "users.email"
+	UserSwiftNS = "users.swift"
+)
+
+// RGWUID is a RGWUID, the content of the index objects that map an access
+// key, email address or swift user to the user owning it. The user is
+// encoded as a "tenant$ns$id" string, see RGWUser.String.
+type RGWUID struct {
+	UserID RGWUser
+}
+
+// UserObject is the content of a user object in UserUIDNS: the RGWUID of the
+// user followed by its RGWUserInfo.
+type UserObject struct {
+	UID  RGWUID
+	Info RGWUserInfo
+}
+
+func DecodeRGWUID(data []byte, opts ...DecodeOptions) (*RGWUID, error) {
+	d := NewDecoder(data, opts...)
+	u, err := d.DecodeRGWUID()
+	if err != nil {
+		return nil, err
+	}
+	return u, d.Done()
+}
+
+// DecodeRGWUID decodes a RGWUID, which Ceph writes as the bare user string
+// without a struct header.
+func (d *Decoder) DecodeRGWUID() (*RGWUID, error) {
+	var r RGWUID
+	d.Field("user_id")
+	s, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	r.UserID = parseRGWUser(s)
+	return &r, nil
+}
+
+func DecodeUserObject(data []byte, opts ...DecodeOptions) (*UserObject, error) {
+	d := NewDecoder(data, opts...)
+	u, err := d.DecodeUserObject()
+	if err != nil {
+		return nil, err
+	}
+	return u, d.Done()
+}
+
+// DecodeUserObject decodes a user object of UserUIDNS.
+func (d *Decoder) DecodeUserObject() (*UserObject, error) {
+	var r UserObject
+	uid, err := d.DecodeRGWUID()
+	if err != nil {
+		return nil, err
+	}
+	r.UID = *uid
+	info, err := d.DecodeRGWUserInfo()
+	if err != nil {
+		return nil, err
+	}
+	r.Info = *info
+	return &r, nil
+}
+
+func (e *Encoder) EncodeRGWUID(r *RGWUID) {
+	e.EncodeString(r.UserID.String())
+}
+
+func EncodeRGWUID(r *RGWUID) []byte {
+	e := NewEncoder()
+	e.EncodeRGWUID(r)
+	return e.Bytes()
+}
+
+func (e *Encoder) EncodeUserObject(r *UserObject) {
+	e.EncodeRGWUID(&r.UID)
+	e.EncodeRGWUserInfo(&r.Info)
+}
+
+func EncodeUserObject(r *UserObject) []byte {
+	e := NewEncoder()
+	e.EncodeUserObject(r)
+	return e.Bytes()
+}
+
+// UserOID returns the name of the user object in UserUIDNS.
+func UserOID(u RGWUser) string {
+	return u.String()
+}
+
+// AccessKeyIndexOID returns the name of the index object of an S3 access key
+// in UserKeysNS.
+func AccessKeyIndexOID(accessKey string) string {
+	return accessKey
+}
+
+// EmailIndexOID returns the name of the index object of an email address in
+// UserEmailNS. RGW stores email addresses in lower case.
+func EmailIndexOID(email string) string {
+	return strings.ToLower(email)
+}
+
+// SwiftIndexOID returns the name of the index object of a swift user like
+// "tenant$user:subuser" in UserSwiftNS.
+func SwiftIndexOID(swiftUser string) string {
+	return swiftUser
+}
+
+// IndexOIDs returns the names of all index objects of u, keyed by their
+// namespace.
+func (u *RGWUserInfo) IndexOIDs() map[string][]string {
+	m := map[string][]string{
+		UserUIDNS: {UserOID(u.UserID)},
+	}
+	for _, id := range sortedAccessKeyIDs(u.AccessKeys) {
+		m[UserKeysNS] = append(m[UserKeysNS], AccessKeyIndexOID(id))
+	}
+	for _, id := range sortedAccessKeyIDs(u.SwiftKeys) {
+		m[UserSwiftNS] = append(m[UserSwiftNS], SwiftIndexOID(id))
+	}
+	if u.Email != "" {
+		m[UserEmailNS] = []string{EmailIndexOID(u.Email)}
+	}
+	return m
+}
@@ -0,0 +1,82 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRGWUID(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     []byte
+		expected RGWUser
+	}{
+		{"plain", EncodeRGWUID(&RGWUID{UserID: RGWUser{ID: "user"}}), RGWUser{ID: "user"}},
+		{"tenant", EncodeRGWUID(&RGWUID{UserID: RGWUser{Tenant: "tenant", ID: "user"}}), RGWUser{Tenant: "tenant", ID: "user"}},
+		{"namespace", EncodeRGWUID(&RGWUID{UserID: RGWUser{Tenant: "tenant", NS: "oidc", ID: "user"}}), RGWUser{Tenant: "tenant", NS: "oidc", ID: "user"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := DecodeRGWUID(tt.data, DecodeOptions{ErrorOnLeftover: true})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, u.UserID)
+		})
+	}
+
+	_, err := DecodeRGWUID([]byte{1, 0})
+	assert.Error(t, err)
+
+	// Whatever follows the user string is left to the leftover policy.
+	data := append(EncodeRGWUID(&RGWUID{UserID: RGWUser{ID: "user"}}), 1, 2)
+	u, err := DecodeRGWUID(data)
+	assert.NoError(t, err)
+	assert.Equal(t, RGWUser{ID: "user"}, u.UserID)
+	_, err = DecodeRGWUID(data, DecodeOptions{ErrorOnLeftover: true})
+	assert.True(t, errors.Is(err, ErrLeftover))
+
+	out, err := json.Marshal(&RGWUID{UserID: RGWUser{Tenant: "tenant", ID: "user"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"user_id": "tenant$user"}`, string(out))
+}
+
+func TestDecodeUserObject(t *testing.T) {
+	// Ceph writes the user string of the RGWUID without a header, then the
+	// RGWUserInfo.
+	info := testUserInfo()
+	e := NewEncoder()
+	e.EncodeString("tenant$user")
+	e.EncodeRGWUserInfo(info)
+
+	u, err := DecodeUserObject(e.Bytes(), DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &UserObject{UID: RGWUID{UserID: info.UserID}, Info: *info}, u)
+	assert.Equal(t, e.Bytes(), EncodeUserObject(u))
+
+	v, err := Decode(UserUIDNS, e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, u, v)
+}
+
+func TestUserIndexOIDs(t *testing.T) {
+	u := &RGWUserInfo{
+		UserID: RGWUser{Tenant: "tenant", ID: "user"},
+		Email:  "User@Example.com",
+		AccessKeys: map[string]RGWAccessKey{
+			"AKIAB": {ID: "AKIAB"},
+			"AKIAA": {ID: "AKIAA"},
+		},
+		SwiftKeys: map[string]RGWAccessKey{
+			"tenant$user:swift": {ID: "tenant$user:swift", Subuser: "swift"},
+		},
+	}
+	assert.Equal(t, map[string][]string{
+		UserUIDNS:   {"tenant$user"},
+		UserKeysNS:  {"AKIAA", "AKIAB"},
+		UserEmailNS: {"user@example.com"},
+		UserSwiftNS: {"tenant$user:swift"},
+	}, u.IndexOIDs())
+}
@@ -0,0 +1,570 @@
+package decoder
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Permissions of RGWSubUser.PermMask and ACLGrant.Permission, the RGW_PERM_*
+// flags.
+const (
+	PermRead        = 0x1
+	PermWrite       = 0x2
+	PermReadACP     = 0x4
+	PermWriteACP    = 0x8
+	PermFullControl = PermRead | PermWrite | PermReadACP | PermWriteACP
+)
+
+// Capability permissions of RGWUserInfo.Caps, the RGW_CAP_* flags.
+const (
+	CapRead  = 0x1
+	CapWrite = 0x2
+	CapAll   = CapRead | CapWrite
+)
+
+// Operations of RGWUserInfo.OpMask, the RGW_OP_TYPE_* flags.
+const (
+	OpTypeRead   = 0x1
+	OpTypeWrite  = 0x2
+	OpTypeDelete = 0x4
+	OpTypeAll    = OpTypeRead | OpTypeWrite | OpTypeDelete
+)
+
+// Defaults of the fields missing from old RGWUserInfo encodings.
+const (
+	defaultMaxBuckets = 1000
+	defaultOpMask     = OpTypeAll
+)
+
+const (
+	userInfoMaxSupportedV  = 22
+	accessKeyMaxSupportedV = 4
+)
+
+// UserType is the source of a user, RGWUserInfo::type.
+type UserType uint32
+
+const (
+	UserTypeNone UserType = iota
+	UserTypeRGW
+	UserTypeKeystone
+	UserTypeLDAP
+)
+
+func (t UserType) String() string {
+	switch t {
+	case UserTypeRGW:
+		return "rgw"
+	case UserTypeKeystone:
+		return "keystone"
+	case UserTypeLDAP:
+		return "ldap"
+	}
+	return "none"
+}
+
+// RGWAccessKey is a RGWAccessKey, a S3 or Swift key of a user or subuser.
+type RGWAccessKey struct {
+	ID         string
+	Key        string
+	Subuser    string
+	Active     bool
+	CreateDate time.Time
+}
+
+// RGWSubUser is a RGWSubUser.
+type RGWSubUser struct {
+	Name     string
+	PermMask uint32
+}
+
+// RGWUserInfo is a RGWUserInfo, the content of the user object <uid> in the
+// users.uid pool.
+type RGWUserInfo struct {
+	UserID           RGWUser
+	DisplayName      string
+	Email            string
+	AccessKeys       map[string]RGWAccessKey
+	SwiftKeys        map[string]RGWAccessKey
+	Subusers         map[string]RGWSubUser
+	Suspended        bool
+	MaxBuckets       int32
+	Caps             map[string]uint32
+	OpMask           uint32
+	System           bool
+	Admin            bool
+	DefaultPlacement RGWPlacementRule
+	PlacementTags    []string
+	BucketQuota      RGWQuotaInfo
+	UserQuota        RGWQuotaInfo
+	TempURLKeys      map[int32]string
+	Type             UserType
+	MFAIDs           []string
+	AssumedRoleARN   string
+}
+
+func DecodeRGWUserInfo(data []byte, opts ...DecodeOptions) (*RGWUserInfo, error) {
+	d := NewDecoder(data, opts...)
+	u, err := d.DecodeRGWUserInfo()
+	if err != nil {
+		return nil, err
+	}
+	return u, d.Done()
+}
+
+// DecodeRGWAccessKey decodes a RGWAccessKey.
+func (d *Decoder) DecodeRGWAccessKey() (*RGWAccessKey, error) {
+	r := RGWAccessKey{Active: true}
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen32("RGWAccessKey", accessKeyMaxSupportedV, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("id")
+	if r.ID, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("key")
+	if r.Key, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("subuser")
+	if r.Subuser, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	if structV >= 3 {
+		d.Field("active")
+		if r.Active, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		d.Field("create_date")
+		if r.CreateDate, err = d.DecodeRealTime(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+// DecodeRGWSubUser decodes a RGWSubUser.
+func (d *Decoder) DecodeRGWSubUser() (*RGWSubUser, error) {
+	var r RGWSubUser
+	_, structEnd, err := d.DecodeStartLegacyCompatLen32("RGWSubUser", 2, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.Field("name")
+	if r.Name, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("perm_mask")
+	if r.PermMask, err = d.DecodeU32(); err != nil {
+		return nil, err
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (d *Decoder) decodeAccessKeyMap(name string) (map[string]RGWAccessKey, error) {
+	m := make(map[string]RGWAccessKey)
+	err := d.DecodeMap(name, func(i uint32) error {
+		id, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		k, err := d.DecodeRGWAccessKey()
+		if err != nil {
+			return err
+		}
+		m[id] = *k
+		return nil
+	})
+	return m, err
+}
+
+// DecodeRGWUserInfo decodes a RGWUserInfo.
+func (d *Decoder) DecodeRGWUserInfo() (*RGWUserInfo, error) {
+	r := RGWUserInfo{
+		AccessKeys: make(map[string]RGWAccessKey),
+		SwiftKeys:  make(map[string]RGWAccessKey),
+		Subusers:   make(map[string]RGWSubUser),
+		Caps:       make(map[string]uint32),
+		MaxBuckets: defaultMaxBuckets,
+		OpMask:     defaultOpMask,
+	}
+	structV, structEnd, err := d.DecodeStartLegacyCompatLen32("RGWUserInfo", userInfoMaxSupportedV, 9, 9)
+	if err != nil {
+		return nil, err
+	}
+	if structV >= 2 {
+		d.Field("auid")
+		if _, err = d.DecodeU64(); err != nil {
+			return nil, err
+		}
+	}
+	d.Field("access_key")
+	accessKey, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	d.Field("secret_key")
+	secretKey, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	if structV < 6 {
+		r.AccessKeys[accessKey] = RGWAccessKey{ID: accessKey, Key: secretKey, Active: true}
+	}
+	d.Field("display_name")
+	if r.DisplayName, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	d.Field("user_email")
+	if r.Email, err = d.DecodeString(); err != nil {
+		return nil, err
+	}
+	// The single swift key of old encodings is ignored, like Ceph does.
+	if structV >= 3 {
+		d.Field("swift_name")
+		if _, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 4 {
+		d.Field("swift_key")
+		if _, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 5 {
+		d.Field("user_id.id")
+		if r.UserID.ID, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.UserID.ID = accessKey
+	}
+	if structV >= 6 {
+		if r.AccessKeys, err = d.decodeAccessKeyMap("access_keys"); err != nil {
+			return nil, err
+		}
+		err = d.DecodeMap("subusers", func(i uint32) error {
+			name, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			s, err := d.DecodeRGWSubUser()
+			if err != nil {
+				return err
+			}
+			r.Subusers[name] = *s
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 7 {
+		d.Field("suspended")
+		if r.Suspended, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 8 {
+		if r.SwiftKeys, err = d.decodeAccessKeyMap("swift_keys"); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 10 {
+		d.Field("max_buckets")
+		if r.MaxBuckets, err = d.DecodeI32(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 11 {
+		d.Field("caps")
+		_, _, capsEnd, err := d.DecodeStart("RGWUserCaps", 1)
+		if err != nil {
+			return nil, err
+		}
+		err = d.DecodeMap("caps", func(i uint32) error {
+			typ, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			perm, err := d.DecodeU32()
+			if err != nil {
+				return err
+			}
+			r.Caps[typ] = perm
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := d.DecodeFinish(capsEnd); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 12 {
+		d.Field("op_mask")
+		if r.OpMask, err = d.DecodeU32(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 13 {
+		d.Field("system")
+		if r.System, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+		d.Field("default_placement")
+		placement, err := d.DecodeRGWPlacementRule()
+		if err != nil {
+			return nil, err
+		}
+		r.DefaultPlacement = *placement
+		if r.PlacementTags, err = d.DecodeStringList("placement_tags"); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 14 {
+		d.Field("bucket_quota")
+		quota, err := d.DecodeRGWQuotaInfo()
+		if err != nil {
+			return nil, err
+		}
+		r.BucketQuota = *quota
+	}
+	if structV >= 15 {
+		r.TempURLKeys = make(map[int32]string)
+		err = d.DecodeMap("temp_url_keys", func(i uint32) error {
+			k, err := d.DecodeI32()
+			if err != nil {
+				return err
+			}
+			v, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			r.TempURLKeys[k] = v
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 16 {
+		d.Field("user_quota")
+		quota, err := d.DecodeRGWQuotaInfo()
+		if err != nil {
+			return nil, err
+		}
+		r.UserQuota = *quota
+	}
+	if structV >= 17 {
+		d.Field("user_id.tenant")
+		if r.UserID.Tenant, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 18 {
+		d.Field("admin")
+		if r.Admin, err = d.DecodeBool(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 19 {
+		d.Field("type")
+		typ, err := d.DecodeU32()
+		if err != nil {
+			return nil, err
+		}
+		r.Type = UserType(typ)
+	}
+	if structV >= 20 {
+		if r.MFAIDs, err = d.DecodeStringSet("mfa_ids"); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 21 {
+		d.Field("assumed_role_arn")
+		if r.AssumedRoleARN, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	if structV >= 22 {
+		d.Field("user_id.ns")
+		if r.UserID.NS, err = d.DecodeString(); err != nil {
+			return nil, err
+		}
+	}
+	return &r, d.DecodeFinish(structEnd)
+}
+
+func (e *Encoder) EncodeRGWAccessKey(r *RGWAccessKey) {
+	start := e.EncodeStart(accessKeyMaxSupportedV, 2)
+	e.EncodeString(r.ID)
+	e.EncodeString(r.Key)
+	e.EncodeString(r.Subuser)
+	e.EncodeBool(r.Active)
+	e.EncodeRealTime(r.CreateDate)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) EncodeRGWSubUser(r *RGWSubUser) {
+	start := e.EncodeStart(2, 2)
+	e.EncodeString(r.Name)
+	e.EncodeU32(r.PermMask)
+	e.EncodeFinish(start)
+}
+
+func (e *Encoder) encodeAccessKeyMap(m map[string]RGWAccessKey) {
+	keys := sortedAccessKeyIDs(m)
+	e.EncodeList(len(keys), func(i int) {
+		k := m[keys[i]]
+		e.EncodeString(keys[i])
+		e.EncodeRGWAccessKey(&k)
+	})
+}
+
+// EncodeRGWUserInfo encodes r in the v22 format. Like Ceph it writes the
+// first access and swift key a second time as the single key of old
+// encodings.
+func (e *Encoder) EncodeRGWUserInfo(r *RGWUserInfo) {
+	start := e.EncodeStart(userInfoMaxSupportedV, 9)
+	e.EncodeU64(0)
+	var accessKey, swiftKey RGWAccessKey
+	if keys := sortedAccessKeyIDs(r.AccessKeys); len(keys) > 0 {
+		accessKey = r.AccessKeys[keys[0]]
+	}
+	if keys := sortedAccessKeyIDs(r.SwiftKeys); len(keys) > 0 {
+		swiftKey = r.SwiftKeys[keys[0]]
+	}
+	e.EncodeString(accessKey.ID)
+	e.EncodeString(accessKey.Key)
+	e.EncodeString(r.DisplayName)
+	e.EncodeString(r.Email)
+	e.EncodeString(swiftKey.ID)
+	e.EncodeString(swiftKey.Key)
+	e.EncodeString(r.UserID.ID)
+	e.encodeAccessKeyMap(r.AccessKeys)
+	subusers := make([]string, 0, len(r.Subusers))
+	for k := range r.Subusers {
+		subusers = append(subusers, k)
+	}
+	sort.Strings(subusers)
+	e.EncodeList(len(subusers), func(i int) {
+		s := r.Subusers[subusers[i]]
+		e.EncodeString(subusers[i])
+		e.EncodeRGWSubUser(&s)
+	})
+	e.EncodeBool(r.Suspended)
+	e.encodeAccessKeyMap(r.SwiftKeys)
+	e.EncodeI32(r.MaxBuckets)
+	caps := e.EncodeStart(1, 1)
+	capTypes := make([]string, 0, len(r.Caps))
+	for k := range r.Caps {
+		capTypes = append(capTypes, k)
+	}
+	sort.Strings(capTypes)
+	e.EncodeList(len(capTypes), func(i int) {
+		e.EncodeString(capTypes[i])
+		e.EncodeU32(r.Caps[capTypes[i]])
+	})
+	e.EncodeFinish(caps)
+	e.EncodeU32(r.OpMask)
+	e.EncodeBool(r.System)
+	e.EncodeRGWPlacementRule(&r.DefaultPlacement)
+	e.EncodeStringList(r.PlacementTags)
+	e.EncodeRGWQuotaInfo(&r.BucketQuota)
+	tempURLKeys := make([]int32, 0, len(r.TempURLKeys))
+	for k := range r.TempURLKeys {
+		tempURLKeys = append(tempURLKeys, k)
+	}
+	sort.Slice(tempURLKeys, func(i, j int) bool { return tempURLKeys[i] < tempURLKeys[j] })
+	e.EncodeList(len(tempURLKeys), func(i int) {
+		e.EncodeI32(tempURLKeys[i])
+		e.EncodeString(r.TempURLKeys[tempURLKeys[i]])
+	})
+	e.EncodeRGWQuotaInfo(&r.UserQuota)
+	e.EncodeString(r.UserID.Tenant)
+	e.EncodeBool(r.Admin)
+	e.EncodeU32(uint32(r.Type))
+	e.EncodeStringList(r.MFAIDs)
+	e.EncodeString(r.AssumedRoleARN)
+	e.EncodeString(r.UserID.NS)
+	e.EncodeFinish(start)
+}
+
+func EncodeRGWUserInfo(r *RGWUserInfo) []byte {
+	e := NewEncoder()
+	e.EncodeRGWUserInfo(r)
+	return e.Bytes()
+}
+
+func sortedAccessKeyIDs(m map[string]RGWAccessKey) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// permString returns mask as rgw_perm_to_str does.
+func permString(mask uint32) string {
+	if mask == 0 {
+		return "<none>"
+	}
+	perms := []struct {
+		mask uint32
+		s    string
+	}{
+		{PermFullControl, "full-control"},
+		{PermRead | PermWrite, "read-write"},
+		{PermRead, "read"},
+		{PermWrite, "write"},
+		{PermReadACP, "read-acp"},
+		{PermWriteACP, "write-acp"},
+	}
+	var l []string
+	for mask != 0 {
+		found := false
+		for _, p := range perms {
+			if mask&p.mask == p.mask {
+				l = append(l, p.s)
+				mask &^= p.mask
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
+// opMaskString returns mask as op_type_to_str does.
+func opMaskString(mask uint32) string {
+	var l []string
+	for i, s := range []string{"read", "write", "delete"} {
+		if mask&(1<<uint(i)) != 0 {
+			l = append(l, s)
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
+// capString returns perm as RGWUserCaps::dump does.
+func capString(perm uint32) string {
+	switch perm & CapAll {
+	case CapAll:
+		return "*"
+	case CapRead:
+		return "read"
+	case CapWrite:
+		return "write"
+	}
+	return ""
+}
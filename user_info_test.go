@@ -0,0 +1,132 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testUserInfo() *RGWUserInfo {
+	return &RGWUserInfo{
+		UserID:      RGWUser{Tenant: "tenant", ID: "user"},
+		DisplayName: "User",
+		Email:       "user@example.com",
+		AccessKeys: map[string]RGWAccessKey{
+			"AKIAB": {ID: "AKIAB", Key: "secret2", Active: true, CreateDate: time.Unix(1600000000, 0).UTC()},
+			"AKIAA": {ID: "AKIAA", Key: "secret1", Subuser: "sub", Active: false},
+		},
+		SwiftKeys: map[string]RGWAccessKey{
+			"tenant$user:sub": {ID: "tenant$user:sub", Key: "swift", Subuser: "sub", Active: true},
+		},
+		Subusers: map[string]RGWSubUser{
+			"sub": {Name: "sub", PermMask: PermRead | PermWrite},
+		},
+		MaxBuckets:       5000,
+		Caps:             map[string]uint32{"users": CapAll, "buckets": CapRead},
+		OpMask:           OpTypeRead | OpTypeDelete,
+		Admin:            true,
+		DefaultPlacement: RGWPlacementRule{Name: "default-placement", StorageClass: "COLD"},
+		PlacementTags:    []string{"ssd"},
+		BucketQuota:      RGWQuotaInfo{MaxSize: -1, MaxObjects: -1, CheckOnRaw: false},
+		UserQuota:        RGWQuotaInfo{MaxSize: 1 << 40, MaxObjects: 1000000, Enabled: true},
+		TempURLKeys:      map[int32]string{1: "temp"},
+		Type:             UserTypeRGW,
+		MFAIDs:           []string{"mfa1"},
+		AssumedRoleARN:   "arn:aws:iam:::role/admin",
+	}
+}
+
+func TestEncodeDecodeRGWUserInfo(t *testing.T) {
+	expected := testUserInfo()
+	data := EncodeRGWUserInfo(expected)
+	actual, err := DecodeRGWUserInfo(data, DecodeOptions{Mode: ModeStrict, ErrorOnLeftover: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, data, EncodeRGWUserInfo(actual))
+}
+
+func TestDecodeRGWUserInfoLegacy(t *testing.T) {
+	// A RGWUserInfo v4, with a u32 version and a single access key.
+	e := NewEncoder()
+	e.EncodeU32(4)
+	e.EncodeU64(0)
+	e.EncodeString("AKIA")
+	e.EncodeString("secret")
+	e.EncodeString("User")
+	e.EncodeString("user@example.com")
+	e.EncodeString("swift")
+	e.EncodeString("swiftkey")
+
+	u, err := DecodeRGWUserInfo(e.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, RGWUser{ID: "AKIA"}, u.UserID)
+	assert.Equal(t, map[string]RGWAccessKey{"AKIA": {ID: "AKIA", Key: "secret", Active: true}}, u.AccessKeys)
+	assert.Empty(t, u.SwiftKeys)
+	assert.Equal(t, int32(1000), u.MaxBuckets)
+	assert.Equal(t, uint32(OpTypeAll), u.OpMask)
+}
+
+func TestDecodeRGWAccessKeyV1(t *testing.T) {
+	// Even v1 keys, with a u32 version, have a subuser.
+	e := NewEncoder()
+	e.EncodeU32(1)
+	e.EncodeString("tenant$user:sub")
+	e.EncodeString("swift")
+	e.EncodeString("sub")
+
+	d := NewDecoder(e.Bytes(), DecodeOptions{ErrorOnLeftover: true})
+	k, err := d.DecodeRGWAccessKey()
+	assert.NoError(t, err)
+	assert.NoError(t, d.Done())
+	assert.Equal(t, &RGWAccessKey{ID: "tenant$user:sub", Key: "swift", Subuser: "sub", Active: true}, k)
+}
+
+func TestRGWUserInfoJSON(t *testing.T) {
+	out, err := json.Marshal(testUserInfo())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"user_id": "tenant$user",
+		"display_name": "User",
+		"email": "user@example.com",
+		"suspended": 0,
+		"max_buckets": 5000,
+		"subusers": [{"id": "tenant$user:sub", "permissions": "read-write"}],
+		"keys": [
+			{"user": "tenant$user:sub", "access_key": "AKIAA", "secret_key": "secret1"},
+			{"user": "tenant$user", "access_key": "AKIAB", "secret_key": "secret2"}
+		],
+		"swift_keys": [{"user": "tenant$user:sub", "secret_key": "swift"}],
+		"caps": [{"type": "buckets", "perm": "read"}, {"type": "users", "perm": "*"}],
+		"op_mask": "read, delete",
+		"admin": true,
+		"default_placement": "default-placement",
+		"default_storage_class": "COLD",
+		"placement_tags": ["ssd"],
+		"bucket_quota": {"max_size": -1, "max_size_kb": 0, "max_objects": -1, "enabled": false, "check_on_raw": false},
+		"user_quota": {"max_size": 1099511627776, "max_size_kb": 1073741824, "max_objects": 1000000, "enabled": true, "check_on_raw": false},
+		"temp_url_keys": [{"key": 1, "val": "temp"}],
+		"type": "rgw",
+		"mfa_ids": ["mfa1"],
+		"assumed_role_arn": "arn:aws:iam:::role/admin"
+	}`, string(out))
+}
+
+func TestPermString(t *testing.T) {
+	testcases := []struct {
+		mask uint32
+		s    string
+	}{
+		{0, "<none>"},
+		{PermRead, "read"},
+		{PermWrite, "write"},
+		{PermRead | PermWrite, "read-write"},
+		{PermFullControl, "full-control"},
+		{PermRead | PermReadACP, "read, read-acp"},
+	}
+	for _, tt := range testcases {
+		assert.Equal(t, tt.s, permString(tt.mask))
+	}
+}
@@ -0,0 +1,68 @@
+package decoder
+
+import "fmt"
+
+// WarningCode identifies a kind of Warning.
+type WarningCode string
+
+// Warning codes of the decoders in this package.
+const (
+	// WarnLegacyCreationTime: the creation time of an old encoding only has
+	// second precision.
+	WarnLegacyCreationTime WarningCode = "legacy_creation_time"
+	// WarnLegacyBucketName: a non-empty legacy bucket name was ignored in
+	// best-effort mode.
+	WarnLegacyBucketName WarningCode = "legacy_bucket_name"
+	// WarnDefaultedIndexPool: an old encoding has no index pool, the data
+	// pool is used instead.
+	WarnDefaultedIndexPool WarningCode = "defaulted_index_pool"
+	// WarnTrimmedKeyName: the name of an old rgw_obj encoding was stripped
+	// of its namespace or "_" prefix.
+	WarnTrimmedKeyName WarningCode = "trimmed_key_name"
+	// WarnRewrittenHead: the first part of an explicit manifest was
+	// replaced by the head object, like Ceph does.
+	WarnRewrittenHead WarningCode = "rewritten_head"
+	// WarnUnknownFields: trailing struct bytes of a newer encoding were
+	// skipped.
+	WarnUnknownFields WarningCode = "unknown_fields"
+)
+
+// Warning reports something a decoder did not decode verbatim, e.g. a value
+// defaulted or normalized for an old encoding. Field is the field path like
+// in DecodeError.
+type Warning struct {
+	Code    WarningCode `json:"code"`
+	Field   string      `json:"field"`
+	Offset  uint32      `json:"offset"`
+	Message string      `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s at offset %d: %s: %s", w.Field, w.Offset, w.Code, w.Message)
+}
+
+// Logger receives the warnings of a decode as they happen. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Warn records a warning for the current field. Decoders of additional types
+// use it like the ones of this package.
+func (d *Decoder) Warn(code WarningCode, format string, args ...interface{}) {
+	w := Warning{
+		Code:    code,
+		Field:   d.fieldPath(),
+		Offset:  d.Offset,
+		Message: fmt.Sprintf(format, args...),
+	}
+	d.warnings = append(d.warnings, w)
+	if d.opts.Logger != nil {
+		d.opts.Logger.Printf("decode warning: %s", w)
+	}
+}
+
+// Warnings returns the warnings recorded so far.
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
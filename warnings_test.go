@@ -0,0 +1,85 @@
+// nolint: scopelint, lll
+package decoder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordLogger []string
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestWarningsOldUserBucketEntry(t *testing.T) {
+	// A cls_user_bucket_entry v6 holding a cls_user_bucket v4.
+	e := NewEncoder()
+	start := e.EncodeStart(6, 5)
+	e.EncodeString("")
+	e.EncodeU64(1)
+	e.EncodeU32(1600000000)
+	e.EncodeU64(2)
+	bucket := e.EncodeStart(4, 3)
+	e.EncodeString("bucket")
+	e.EncodeString("data")
+	e.EncodeString("marker")
+	e.EncodeString("id")
+	e.EncodeFinish(bucket)
+	e.EncodeU64(4096)
+	e.EncodeBool(false)
+	e.EncodeFinish(start)
+
+	var logger recordLogger
+	var report DecodeReport
+	u, err := DecodeUserBucketEntry(e.Bytes(), DecodeOptions{Logger: &logger, Report: &report})
+	assert.NoError(t, err)
+	assert.Equal(t, "data", u.Bucket.ExplicitPlacement.IndexPool)
+	assert.Equal(t, []Warning{
+		{
+			Code:    WarnLegacyCreationTime,
+			Field:   "cls_user_bucket_entry.creation_time",
+			Offset:  22,
+			Message: "struct_v 6 stores the creation time in seconds",
+		},
+		{
+			Code:    WarnDefaultedIndexPool,
+			Field:   "cls_user_bucket_entry.bucket.explicit_placement.index_pool",
+			Offset:  70,
+			Message: `struct_v 4 has no index pool, using data pool "data"`,
+		},
+	}, report.Warnings)
+	assert.Equal(t, recordLogger{
+		"decode warning: cls_user_bucket_entry.creation_time at offset 22: legacy_creation_time: struct_v 6 stores the creation time in seconds",
+		`decode warning: cls_user_bucket_entry.bucket.explicit_placement.index_pool at offset 70: defaulted_index_pool: struct_v 4 has no index pool, using data pool "data"`,
+	}, logger)
+}
+
+func TestWarningsUnknownFields(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bucket_entry_1")
+	assert.NoError(t, err)
+	data[2]++
+	data = append(data, 0)
+
+	d := NewDecoder(data)
+	_, err = d.DecodeUserBucketEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{{
+		Code:    WarnUnknownFields,
+		Field:   "cls_user_bucket_entry",
+		Offset:  uint32(len(data) - 1),
+		Message: "skipped 1 bytes of unknown fields",
+	}}, d.Warnings())
+}
+
+func TestWarningsNone(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/manifest_1")
+	assert.NoError(t, err)
+	var report DecodeReport
+	_, err = DecodeRGWObjManifest(data, DecodeOptions{Report: &report})
+	assert.NoError(t, err)
+	assert.Empty(t, report.Warnings)
+}